@@ -0,0 +1,47 @@
+package flagbind
+
+import "net"
+
+// TCPAddr is a net.TCPAddr that is parsed from `host:port` using
+// net.ResolveTCPAddr.
+type TCPAddr net.TCPAddr
+
+func (a *TCPAddr) Set(text string) error {
+	addr, err := net.ResolveTCPAddr("tcp", text)
+	if err != nil {
+		return err
+	}
+	*a = TCPAddr(*addr)
+	return nil
+}
+
+func (a TCPAddr) String() string {
+	if a.IP == nil && a.Port == 0 && a.Zone == "" {
+		return ""
+	}
+	return (*net.TCPAddr)(&a).String()
+}
+
+func (a TCPAddr) Type() string { return "tcpAddr" }
+
+// UDPAddr is a net.UDPAddr that is parsed from `host:port` using
+// net.ResolveUDPAddr.
+type UDPAddr net.UDPAddr
+
+func (a *UDPAddr) Set(text string) error {
+	addr, err := net.ResolveUDPAddr("udp", text)
+	if err != nil {
+		return err
+	}
+	*a = UDPAddr(*addr)
+	return nil
+}
+
+func (a UDPAddr) String() string {
+	if a.IP == nil && a.Port == 0 && a.Zone == "" {
+		return ""
+	}
+	return (*net.UDPAddr)(&a).String()
+}
+
+func (a UDPAddr) Type() string { return "udpAddr" }
@@ -0,0 +1,28 @@
+package flagbind
+
+import "fmt"
+
+// Alias defines the flag old as a deprecated alias of the flag name, which must
+// already be defined in fs, so that a renamed flag keeps working for a
+// release cycle. Using old sets name, and prints a warning to use name
+// instead. With pflag, old is hidden. See also the `renamed-from` tag option.
+func Alias(fs FlagSet, old, name string) error {
+	if flagValue(fs, name) == nil {
+		return fmt.Errorf("cannot alias undefined flag: %q", name)
+	}
+	if flagValue(fs, old) != nil {
+		return fmt.Errorf("flag already defined: %q", old)
+	}
+	legacyFlag(fs, name, old)
+	return nil
+}
+
+// legacyFlag defines old as a deprecated alias of the flag name.
+func legacyFlag(fs FlagSet, name, old string) {
+	dashes := "-"
+	if _, ok := fs.(PFlagSet); ok {
+		dashes = "--"
+	}
+	aliasFlag(fs, name, old)
+	deprecateFlag(fs, old, fmt.Sprintf("use %v%v instead", dashes, name))
+}
@@ -0,0 +1,47 @@
+package flagbind
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlias(t *testing.T) {
+	var f struct {
+		Timeout int `flag:";5;;renamed-from=wait|delay"`
+		Retries int
+	}
+
+	var out bytes.Buffer
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.SetOutput(&out)
+	require.NoError(t, Bind(fs, &f, Prefix("app-")))
+	require.NoError(t, Alias(fs, "app-tries", "app-retries"))
+	assert.True(t, fs.Lookup("app-wait").Hidden)
+	assert.Equal(t, "5", fs.Lookup("app-delay").DefValue)
+	require.NoError(t, fs.Parse([]string{"--app-wait", "10", "--app-tries", "2"}))
+	assert.Equal(t, 10, f.Timeout)
+	assert.Equal(t, 2, f.Retries)
+	assert.Equal(t, "Flag --app-wait has been deprecated, "+
+		"use --app-timeout instead\n"+
+		"Flag --app-tries has been deprecated, use --app-retries instead\n",
+		out.String())
+
+	assert.EqualError(t, Alias(fs, "old", "missing"),
+		`cannot alias undefined flag: "missing"`)
+	assert.EqualError(t, Alias(fs, "app-wait", "app-retries"),
+		`flag already defined: "app-wait"`)
+
+	out.Reset()
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	std.SetOutput(&out)
+	require.NoError(t, Bind(std, &f))
+	require.NoError(t, std.Parse([]string{"-delay", "3"}))
+	assert.Equal(t, 3, f.Timeout)
+	assert.Equal(t, "Flag -delay has been deprecated, use -timeout instead\n",
+		out.String())
+}
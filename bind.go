@@ -90,20 +90,28 @@
 package flagbind
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"net/mail"
 	"net/url"
+	"os"
 	"reflect"
 	"strings"
 	"time"
+	"unicode"
+	"unsafe"
 
 	"github.com/spf13/pflag"
 )
 
 // Separator is used to separate a prefix from a flag name and as the separator
-// passed to FromCamelCase.
+// passed to FromCamelCase. It is only used by calls to Bind without the
+// SeparatorOpt Option.
 var Separator = "-"
 
 // Binder binds itself to a FlagSet.
@@ -117,10 +125,25 @@ var Separator = "-"
 //
 // The underlying type of `fs` is the same as the original FlagSet passed to
 // Bind.
+//
+// Use OptionContext to obtain the context.Context passed to BindContext.
 type Binder interface {
 	FlagBind(fs FlagSet, prefix string, opt Option) error
 }
 
+// FieldBinder is like Binder, but is also passed the struct field that it is
+// bound from, so that it may use the field name and its Flag Tag. If a field
+// implements both, only FlagBindField is called.
+//
+// Unlike Binder, `prefix` is the prefix of the struct that contains the field,
+// and does not include a prefix derived from the field itself. The field's own
+// flag would typically be named `prefix` followed by the name in its Flag Tag,
+// or else the field name passed through FromCamelCase.
+type FieldBinder interface {
+	FlagBindField(fs FlagSet, field reflect.StructField, prefix string,
+		opt Option) error
+}
+
 // Bind the exported fields of struct `v` to new flags in the FlagSet `fs`.
 //
 // Bind returns ErrorInvalidFlagSet if `fs` does not implement STDFlagSet or
@@ -128,15 +151,33 @@ type Binder interface {
 //
 // Bind returns ErrorInvalidType if `v` is not a pointer to a struct.
 //
-// Bind recovers from FlagSet panics and instead returns the panic as an error
-// if a duplicate flag name occurs.
+// Bind returns ErrorDuplicateFlag, which identifies both struct fields, if two
+// fields map to the same flag name. Bind recovers from FlagSet panics and
+// instead returns the panic as an error if a flag name is otherwise
+// duplicated, such as by a flag defined before Bind was called.
+//
+// Bind returns ErrorAliasedField if two fields refer to the same memory, such
+// as a shared struct pointer, since both flags would silently set the same
+// value.
 //
 // For each exported field of `v` Bind attempts to define one or more
 // corresponding flags in `fs` according to the following rules.
 //
 // If the field is a nil pointer, it is initialized.
 //
+// If `v`, or a nested struct, implements DefaultsSetter, then SetDefaults is
+// called before its fields are bound.
+//
+// If the field is an interface that holds a non-nil pointer, then the pointer
+// is bound according to these same rules, so a struct pointer assigned to the
+// interface has its fields bound. Otherwise the interface is skipped.
+//
+// If a TypeHandler registered with RegisterType handles the field, then it is
+// bound as the returned flag.Value.
+//
 // If the field implements Binder, then only FlagBind is called on the field.
+// Likewise, if the field implements FieldBinder, then only FlagBindField is
+// called.
 //
 // If the field implements flag.Value and not Binder, then it is bound as a
 // Value on the FlagSet.
@@ -144,14 +185,38 @@ type Binder interface {
 // Otherwise, if the field is a struct, or struct pointer, then Bind is
 // recursively called on a pointer to the struct field.
 //
+// Flags are defined in the order that their fields are declared, depth first.
+// See the DeclarationOrder Option to also list them in this order in the usage.
+//
 // If the field is any supported type, a new flag is defined in `fs` with the
 // settings defined in the field's `flag:"..."` tag. If the field is non-zero,
 // its value is used as the default for that flag instead of whatever is
 // defined in the `flag:";<default>"` tag. See FlagTag Settings below.
 //
-// For a complete list of supported types see STDFlagSet and PFlagSet.
+// For a complete list of supported types see STDFlagSet and PFlagSet. The
+// int8, int16, int32, uint8, uint16, and uint32 types are also supported when
+// using the standard flag package, with range checking.
+//
 // Additionally, a json.RawMessage is also natively supported and is bound as a
-// JSONRawMessage flag.
+// JSONRawMessage flag, an os.FileMode is bound as an octal FileMode flag, and
+// a []byte is bound as a BytesHex or BytesBase64 flag depending on the
+// encoding option. A time.Time or []time.Time is parsed using the layout
+// option, and a []time.Time may be repeated. The <default> of a time.Time may
+// also be relative to when Bind is called, such as "now", "now-24h", "today",
+// or "today+9h", where "today" is the start of the current day. A
+// mail.Address is parsed using mail.ParseAddress, and a net.TCPAddr or
+// net.UDPAddr is resolved from `host:port` when the flag is set. A
+// *time.Location is loaded by name using time.LoadLocation. A
+// map[string]string is bound with a flag.Value that mirrors a pflag
+// StringToString, so that repeated key=value pairs accumulate. Likewise, a map
+// with string keys and values that implement encoding.TextUnmarshaler accepts
+// a repeated key=value flag, with each value passed to UnmarshalText.
+//
+// A field of type func(string) error is bound as an action flag that calls
+// the func with the value each time the flag is set, like flag.Func, such as
+// for an accumulating --add-header flag. A field of type func() error is bound
+// as a bool flag that calls the func each time the flag is set to true. A
+// nil func is skipped.
 //
 //
 // Ignoring a Field
@@ -179,12 +244,21 @@ type Binder interface {
 // If `fs` does not implement PFlagSet, then the short name is ignored if a
 // long name is defined, otherwise the short name is used as the long name.
 //
+// Use the StrictShortNames Option to have Bind return an error instead of
+// ignoring a short name.
+//
 // If `fs` does implement PFlagSet, and only a short flag is defined, the long
 // name defaults to the field name in kebab-case.
 //
 // If no name is set, the long name defaults to the field name in "kebab-case".
 // For example, "ThisFieldName" becomes "this-field-name". See FromCamelCase
-// and Separator.
+// and Separator. An alternative Splitter may be set using the NameSplitter
+// Option, or the VerbatimNames Option may be used to keep field names as is.
+// For full control, use the NameFunc Option.
+//
+// The NameTags Option derives the name from another struct tag, such as
+// `mapstructure` or `envconfig`, and the UseJSONNames Option uses the name in
+// the `json` tag, if it is set, before any of the above.
 //
 // If the field is a nested or embedded struct and the "flatten" option is not
 // set (see below), then the name is used as a prefix for all nested field flag
@@ -193,24 +267,164 @@ type Binder interface {
 //
 // <default> - Bind attempts to parse <default> as the field's default, just
 // like it would be parsed as a flag. Non-zero field values override this as
-// the default.
+// the default. If there is no <default>, it is computed at bind time by the
+// func named by the `default-func` <option>, or else by the field's
+// DefaultFlagValue method if it implements Defaulter. A flag is never
+// considered set by its default, so it is not visited by FlagSet.Visit and,
+// with pflag, is not Changed.
 //
 //
 // <usage> - The usage string for the flag. See Extended Usage below for a way
 // to break longer usage strings across multiple lines.
 //
 //
+// Bind returns ErrorFlagTag if a tag is malformed, such as when it has more
+// than four settings, more than two names, or an empty <option>.
+//
+//
 // <options> - A comma separated list of additional options for the flag.
+// Options that take a value use the form `<option>=<value>`, and the value may
+// be a list separated by "|", such as `choices=json|yaml`.
+// Bind returns ErrorTagOption if an option is not known, such as a misspelled
+// `hide-defualt`.
 //
 //      hide-default - Do not print the default value of this flag in the usage
 //      output.
 //
+//      default-func=<name> - Compute the <default> at bind time with the func
+//      registered with RegisterDefaultFunc under <name>.
+//
+//      aliases=<name>[|<name>...] - Define additional flags with each name,
+//      prefixed like the flag name, that set the same field, such as former
+//      names of a renamed flag. With pflag, the aliases are hidden.
+//
+//      renamed-from=<name>[|<name>...] - Like aliases, but each name is
+//      deprecated, so using it prints a warning to use the flag name instead.
+//      See Alias.
+//
+//      deprecated=<message> - Mark the flag as deprecated. With pflag, the
+//      flag is hidden and using it prints "Flag --<name> has been deprecated,
+//      <message>". With the standard flag package, the message is added to the
+//      usage and a similar message is printed when the flag is used.
+//
+//      requires=<name>[|<name>...] - The flag may only be set if each of
+//      the named flags is also set, such as `requires=tls-cert`. The names
+//      are full flag names, including any prefix. This is checked by
+//      Validate, not Bind.
+//
+//      conflicts=<name>[|<name>...] - The flag may not be set if any of the
+//      named flags is also set, such as `conflicts=quiet`. The names are full
+//      flag names, including any prefix. This is checked by Validate, not
+//      Bind.
+//
+//      oneof=<group> - Exactly one of the flags with the same <group> must be
+//      set, such as `oneof=input` on each of --file, --url, and --stdin. Use
+//      `at-most-one=<group>` to also allow none of them to be set. Groups
+//      are not prefixed. This is checked by Validate, not Bind.
+//
+//      key=<key> - The key to look up in the ValueSource set by the
+//      DefaultsFrom Option, instead of the flag name.
+//
+//      onchange=<name> - Call the func registered with RegisterOnChange
+//      under <name> each time the flag is set, including by Resolve and
+//      Watch.
+//
 //      hidden - (PFlagSet only) Do not show this flag in the usage output.
+//      On a nested struct, or a map or slice of structs, this hides every
+//      flag that it defines, which are still registered.
+//
+//      skip-zero-default - Do not print the default value of this flag in the
+//      usage output if the field has the zero value of its type, such as
+//      `(default [])`. See also the HideZeroDefaults Option.
 //
 //      flatten - (Nested/embedded structs only) Do not prefix the name of the
 //      struct to the names of its fields. This overrides any explicit name on
 //      an embedded struct which would otherwise unflatten it.
 //
+//      count - (int only) Each occurrence of the flag increments the value,
+//      such as `-v -v -v`. This uses CountVarP if fs is a PFlagSet.
+//
+//      json - Bind a single flag that accepts JSON unmarshaled into the
+//      field with encoding/json. This works for any struct, map, slice, or
+//      other type. On a nested struct, this replaces the flags for each of
+//      its fields with one flag named like the prefix would be. The alias
+//      inline-json may also be used.
+//
+//      layout=<layout> - (time.Time and []time.Time only) The layout used
+//      to parse and print times. This may be the name of any layout constant
+//      in the time package, such as RFC1123 or DateOnly, or a layout that
+//      does not contain a comma. The default is RFC3339Nano. Each occurrence
+//      of a []time.Time flag accepts comma separated times, unless the
+//      layout contains a comma, as RFC1123 does, in which case it accepts
+//      one time.
+//
+//      array - (PFlagSet []string only) Use StringArrayVarP instead of
+//      StringSliceVarP so that values are not split on commas.
+//
+//      merge - (Maps, json.RawMessage, and json only) Each occurrence
+//      of the flag is merged into the existing value, including any default,
+//      instead of replacing it. JSON objects are merged recursively.
+//
+//      schemes=<scheme>[|<scheme>...] - (url.URL only) The URL must use one
+//      of the listed schemes, such as `schemes=https|wss`.
+//
+//      require-host - (url.URL only) The URL must be absolute with a host.
+//
+//      choices=<choice>[|<choice>...] - The value must be one of the listed
+//      choices, such as `choices=json|yaml|text`. Shell completion scripts
+//      complete the choices.
+//
+//      normalize=<name>[|<name>...] - Normalize the value with each named
+//      normalizer, in order, before it is set and before any choices are
+//      checked, such as `normalize=trim|lower`. The "trim", "lower", and
+//      "upper" normalizers are built in, and others may be registered with
+//      RegisterNormalizer.
+//
+//      expand-file - A value of the form `@<path>` is replaced by the
+//      contents of the file at <path>, with any single trailing newline
+//      removed. Use `@@` for a value that begins with a literal `@`.
+//
+//      expand-env - Environment variables in the value, including the tag
+//      <default>, are expanded using os.ExpandEnv, such as
+//      `flag:";$HOME/.config/app;;expand-env"`. When used with expand-file,
+//      variables are expanded first.
+//
+//      sensitive - Redact the value of the flag wherever it is displayed,
+//      including its default in the usage, Effective, Handler, and
+//      DumpValues, like a Secret, but for a field of any type, such as a
+//      []byte key. The field still holds the actual value.
+//
+//      secret - (string only) Bind the string as a Secret so that its value
+//      is never displayed.
+//
+//      secret=<scheme>:<ref> - Like secret, but also resolve the value of the
+//      flag, if it is not set, with the resolver registered for <scheme> with
+//      RegisterSecretResolver, such as `secret=aws-ssm:/app/db-password`. See
+//      SecretLayer.
+//
+//      precedence=<origin>[|<origin>...] - The Origins that Resolve may set
+//      the flag from, in order of precedence, by name, such as
+//      `precedence=env|flag` for a flag that must never come from a config
+//      file. See Resolve.
+//
+//      exists, not-exists, readable, create - (File only) Check the file
+//      when the flag is set. See File.
+//
+//      create - (Dir only) Create the directory if it does not exist.
+//
+//      encoding=<hex|base64> - ([]byte only) The encoding of the flag value.
+//      The default is hex.
+//
+//      sep=<separator> - (Nested structs, and maps and slices of structs,
+//      only) The separator appended to the prefix, instead of Separator. See
+//      Nested/Embedded Structs Flag Prefix.
+//
+//      keys=<key>[|<key>...] - (Maps of structs only) The keys to bind. See
+//      Maps and Slices of Structs.
+//
+//      len=<n> - (Slices of structs only) The number of elements to bind. See
+//      Maps and Slices of Structs.
+//
 //
 // Extended Usage
 //
@@ -252,7 +466,11 @@ type Binder interface {
 //
 // To allow for a distinct separator symbol to be used just for a prefix, an
 // explicitly set prefix that ends in "-", "_", or "." will not have Separator
-// appended.
+// appended. Alternatively, the `sep=<separator>` <option> on the parent's Flag
+// Tag sets the separator appended to its prefix. For example, both
+// `flag:"db.;"` and `flag:"db;;;sep=."` on a nested struct with a Host field
+// bind -db.host. For maps and slices of structs, the separator is also used
+// after each key or index.
 //
 // By default, flags in nested structs always have a prefix, but this can be
 // omitted with Flag Tag `flatten` <option>.
@@ -260,6 +478,36 @@ type Binder interface {
 // By default, flags in embedded structs do not given a prefix, but one can be
 // added by setting an explicit Flag Tag <name>.
 //
+// The exported fields of an embedded struct of an unexported type, such as
+// `struct{ config }`, are also bound, since they are promoted. An embedded
+// pointer to an unexported type is skipped if it is nil, since it cannot be
+// allocated.
+//
+//
+// Maps and Slices of Structs
+//
+// A map with string keys and struct or struct pointer values binds the fields
+// of the struct once for each key, with the prefix `<name>-<key>-`. The keys
+// are set by the MapKeys Option, or else the `keys` <option>, or else the
+// existing keys of the map are used. For example, this binds the flags
+// -servers-primary-host and -servers-backup-host.
+//
+//      type Flags struct {
+//              Servers map[string]ServerFlags `flag:";;;keys=primary|backup"`
+//      }
+//
+// The map is allocated if nil, and any existing value for a key is used for its
+// defaults.
+//
+// Similarly, a slice of structs or struct pointers binds the fields of each
+// element with the prefix `<name>-<index>-`. The slice is grown to the `len`
+// <option> if it is shorter. For example, this binds -endpoints-0-url and
+// -endpoints-1-url.
+//
+//      type Flags struct {
+//              Endpoints []Endpoint `flag:";;;len=2"`
+//      }
+//
 //
 // Overriding Flag Settings
 //
@@ -267,7 +515,26 @@ type Binder interface {
 // type, such as when the type is from an external package. To allow for
 // setting the default value, usage, or other options, an Overriding Flag Tag
 // may be specified on a blank identifier field (`_`) that occurs anywhere
-// after the field that defined the overridden flag.
+// after the field that defined the overridden flag. The `hidden` and
+// `deprecated` options may be used to hide or deprecate flags defined by types
+// that you do not control, and a hidden flag is never unhidden by an override.
+//
+// If the name in an Overriding Flag Tag ends in "*", the override applies to
+// every flag already defined whose name begins with the preceding prefix. For
+// example, this hides all flags of an embedded http.Client prefixed with
+// "client-".
+//
+//      type Flags struct {
+//              Client http.Client
+//              _ struct{} `flag:"client-*;;;hidden"`
+//      }
+//
+// If `fs` implements PFlagSet, an Overriding Flag Tag may also set a short
+// name, such as `flag:"timeout,t"`. Since pflag cannot add a short name to an
+// existing flag, the short name is applied when the flag is defined by a
+// field of the same struct, or of a struct nested within it. Bind returns
+// ErrorFlagOverrideShortName if the flag was defined in any other way, such as
+// by a Binder.
 //
 // The name specified in the Overriding Flag Tag must exactly match the flag
 // name of the overridden flag, including any prefixes that were prepended due
@@ -286,11 +553,50 @@ type Binder interface {
 //              _ struct{} `use:"... continued usage"`
 //      }
 func Bind(fs FlagSet, v interface{}, opts ...Option) error {
-	return newBind(opts...).bind(fs, v)
+	return BindContext(context.Background(), fs, v, opts...)
+}
+
+// BindContext is like Bind, but binds with the given ctx, which is available
+// to Binder and FieldBinder implementations through OptionContext.
+// BindContext returns ctx.Err() if ctx is done before a struct is bound.
+func BindContext(ctx context.Context, fs FlagSet, v interface{},
+	opts ...Option) error {
+	b := newBind(opts...)
+	// A Binder that calls Bind with its Option keeps the caller's context.
+	if !b.Nested || b.Context == nil {
+		b.Context = ctx
+	}
+	if !b.Nested {
+		b.setNormalizeFunc(fs)
+		b.State.recordExisting(fs)
+	}
+	if err := b.bind(fs, v); err != nil {
+		if b.Nested {
+			return err
+		}
+		return b.handleError(fs, err)
+	}
+	if !b.Nested {
+		b.applyUsageFunc(fs)
+		b.setUsage(fs)
+		for _, origin := range b.State.Origins {
+			setOrigin(fs, origin.Name, origin.Origin)
+		}
+		for name, tag := range b.State.Tags {
+			if tag.Precedence != nil {
+				setPrecedence(fs, name, tag.Precedence)
+			}
+		}
+	}
+	return nil
 }
 
 func (b bind) bind(fs FlagSet, v interface{}) (err error) {
 
+	if err := b.context().Err(); err != nil {
+		return err
+	}
+
 	// Hand control over to the Binder implementation.
 	if binder, ok := v.(Binder); ok {
 		return binder.FlagBind(fs, b.Prefix, b.Option())
@@ -313,6 +619,11 @@ func (b bind) bind(fs FlagSet, v interface{}) (err error) {
 		return ErrorInvalidType{v, false}
 	}
 
+	// Set programmatic defaults before any field values are read.
+	if setter, ok := v.(DefaultsSetter); ok && !b.NoDefaults {
+		setter.SetDefaults()
+	}
+
 	// The flag and pflag packages panic when a flag with a duplicate name
 	// is defined. This works well for identifying the offending line of
 	// code where the flag name is redefined, but that is just noise to
@@ -328,58 +639,106 @@ func (b bind) bind(fs FlagSet, v interface{}) (err error) {
 	}()
 
 	_, usePFlag := fs.(PFlagSet)
+	if _, ok := fs.(STDFlagSet); !ok && !usePFlag {
+		return ErrorInvalidFlagSet
+	}
 
-	valT := val.Type()
-
-	defaults := make(map[string]string)
+	fields := cachedFields(val.Type())
+	if usePFlag {
+		b.State.addOverrideShortNames(fields)
+	}
 
 	// loop through all fields
-	for i := 0; i < val.NumField(); i++ {
-
-		structField := valT.Field(i)
+	for _, field := range fields {
 
-		// Special flag metadata may be set using the blank identifier.
-		isMetadata := structField.Name == "_"
+		structField := field.StructField
+		isMetadata := field.IsMetadata
+		hasTag := field.HasTag
+		tag := field.Tag
 
-		// See reflect.StructField for details.
-		isExported := structField.PkgPath == ""
-
-		// Ignore unexported, non-metadata fields.
-		if !isExported && !isMetadata {
-			continue
+		if field.TagErr != nil {
+			return ErrorFlagTag{structField.Name,
+				structField.Tag.Get("flag"), field.TagErr}
 		}
 
-		// Parse the flagTag.
-		tagStr, hasTag := structField.Tag.Lookup("flag")
-		tag := newFlagTag(tagStr)
-
-		if tag.IsIgnored {
-			continue
+		if b.StrictShortNames && !isMetadata {
+			if tag.InvalidShortName != "" {
+				return ErrorShortName{structField.Name,
+					tag.InvalidShortName}
+			}
+			if !usePFlag && tag.ShortName != "" &&
+				tag.ShortName != tag.Name {
+				return ErrorShortName{structField.Name,
+					tag.ShortName}
+			}
 		}
 
 		// Auto populate name if it has no explicit name, or only has a
 		// short name.
 		if !tag.HasExplicitName ||
 			(usePFlag && tag.Name == tag.ShortName) {
-			tag.Name = FromCamelCase(structField.Name, Separator)
+			tag.Name = b.flagName(field)
 		}
 
-		fieldV := val.Field(i)
+		tag.ExtendedDuration = b.ExtendedDurations
+		tag.Unchecked = b.Unchecked
+
+		// Reject misspelled options rather than silently ignoring
+		// them.
+		if len(tag.UnknownOptions) > 0 {
+			name := tag.Name
+			if !isMetadata {
+				name = b.Prefix + name
+			}
+			return ErrorTagOption{name, tag.UnknownOptions[0]}
+		}
 
-		i = loadExtendedUsage(i, valT, &tag)
+		if b.Translate != nil && tag.Usage != "" {
+			if usage := b.Translate(tag.Usage); usage != "" {
+				tag.Usage = usage
+			}
+		}
+
+		fieldV := val.Field(structField.Index[0])
 
 		// Update Flag with Metadata tag.
 		if isMetadata {
 			if hasTag {
-				if err := overrideFlag(fs, tag); err != nil {
+				err := overrideFlag(fs, tag)
+				// The flag may have been filtered out.
+				if _, ok := err.(ErrorFlagOverrideUndefined); ok &&
+					b.hasFlagFilter() {
+					err = nil
+				}
+				if err != nil {
 					return err
 				}
 			}
 			continue
 		}
 
-		// Ensure we are dealing with a pointer.
-		if structField.Type.Kind() != reflect.Ptr {
+		path := b.fieldPath(structField.Name)
+
+		// The exported fields of an embedded struct of an unexported
+		// type are bound, but its value must be made settable first.
+		if structField.PkgPath != "" {
+			if fieldV = settable(fieldV); !fieldV.IsValid() {
+				continue
+			}
+		}
+
+		// Ensure we are dealing with a pointer. A *time.Location is
+		// replaced, not set, so we need a pointer to the field. An
+		// interface is bound through the non-nil pointer that it holds,
+		// if any.
+		if structField.Type.Kind() == reflect.Interface {
+			if fieldV.IsNil() || fieldV.Elem().Kind() != reflect.Ptr ||
+				fieldV.Elem().IsNil() {
+				continue
+			}
+			fieldV = fieldV.Elem()
+		} else if structField.Type.Kind() != reflect.Ptr ||
+			structField.Type == locationType {
 			fieldV = fieldV.Addr()
 		}
 
@@ -391,18 +750,75 @@ func (b bind) bind(fs FlagSet, v interface{}) (err error) {
 			fieldV.Set(reflect.New(fieldT))
 		}
 
+		// Detect fields that share memory with a field that has
+		// already been bound, such as a struct pointer reachable
+		// through two different fields. Zero sized types may share
+		// addresses without aliasing anything.
+		if fieldT.Size() > 0 {
+			key := fieldKey{fieldV.Pointer(), fieldT}
+			if path, ok := b.State.Fields[key]; ok {
+				return ErrorAliasedField{structField.Name, path}
+			}
+			b.State.Fields[key] = path
+		}
+
 		fieldI := fieldV.Interface()
 
+		// Registered types take precedence over everything else.
+		if val, ok := registeredValue(fieldI); ok {
+			fieldI = val
+		}
+
+		fieldBinder, isFieldBinder := fieldI.(FieldBinder)
 		_, isBinder := fieldI.(Binder)
+		isBinder = isBinder || isFieldBinder
 
-		_, isFlagValue := fieldI.(flag.Value)
-		_, isJSONRawMessage := fieldI.(*json.RawMessage)
-		_, isURL := fieldI.(*url.URL)
-		_, isMarshaler := fieldI.(textBidiMarshaler)
-		noDive := isFlagValue || isJSONRawMessage || isURL || isMarshaler
+		noDive := isValue(fieldI)
 
 		isStruct := fieldT.Kind() == reflect.Struct
 
+		// Any field may be bound as a single JSON flag, including
+		// nested structs which would otherwise have a flag for each of
+		// their fields.
+		if !isBinder && tag.JSON {
+			fieldI = &jsonValue{fieldV, tag.Merge}
+			noDive = true
+		}
+
+		// Maps of encoding.TextUnmarshaler values are bound as a
+		// repeated key=value flag.
+		if !isBinder && !noDive && isTextMap(fieldT) {
+			fieldI = newTextMapValue(fieldV, tag.Merge)
+		}
+
+		// Other maps of structs bind the fields of the struct for
+		// each key, and slices of structs for each index.
+		if !isBinder && !noDive && !isTextMap(fieldT) &&
+			isStructMap(fieldT) {
+			order := len(b.State.Order)
+			err := b.bindStructMap(fs, fieldV.Elem(), tag,
+				structField.Name, path)
+			if err != nil {
+				return err
+			}
+			if tag.Hidden {
+				b.State.hideFlags(fs, order)
+			}
+			continue
+		}
+		if !isBinder && !noDive && isStructSlice(fieldT) {
+			order := len(b.State.Order)
+			err := b.bindStructSlice(fs, fieldV.Elem(), tag,
+				structField.Name, path)
+			if err != nil {
+				return err
+			}
+			if tag.Hidden {
+				b.State.hideFlags(fs, order)
+			}
+			continue
+		}
+
 		// If the field implements Binder, we call Bind on the field,
 		// which will call its Binder implementation.
 		//
@@ -414,6 +830,7 @@ func (b bind) bind(fs FlagSet, v interface{}) (err error) {
 		if isBinder || (!noDive && isStruct) {
 
 			// Set prefix up to this point.
+			prefix := b.Prefix
 			b := b
 
 			// If the nested field is not explicitly flattened AND
@@ -423,63 +840,536 @@ func (b bind) bind(fs FlagSet, v interface{}) (err error) {
 			if !tag.Flatten &&
 				(b.NoAutoFlatten ||
 					!structField.Anonymous || tag.HasExplicitName) {
-				b.Prefix += tag.Name
+				b.Prefix = b.nestedPrefix(b.Prefix, tag, tag.Name)
+			} else {
+				b.Prefix = b.joinPrefix(b.Prefix, "")
+			}
+			b.Path = path
+
+			order := len(b.State.Order)
+			var err error
+			if isFieldBinder {
+				err = fieldBinder.FlagBindField(fs, structField,
+					prefix, b.Option())
+			} else {
+				err = b.bind(fs, fieldI)
+			}
+			if err != nil {
+				return newErrorNestedStruct(structField.Name, err)
+			}
+			if isBinder {
+				// Attribute any flags defined directly by the
+				// Binder to its field.
+				b.State.recordFlags(fs, b.Path)
+			}
+			if tag.Hidden {
+				b.State.hideFlags(fs, order)
 			}
+			continue
+		}
 
-			b.Prefix = appendSeparator(b.Prefix)
+		if !hasTag && b.IgnoreUntagged {
+			continue
+		}
+		if !hasTag && b.RequireTags {
+			return ErrorMissingTag{structField.Name}
+		}
 
-			if err := b.bind(fs, fieldI); err != nil {
-				return newErrorNestedStruct(structField.Name, err)
+		tag.Name = b.Prefix + tag.Name
+		if b.hasFlagFilter() && !b.includeFlag(tag.Name) {
+			continue
+		}
+		if b.OnRename != nil && b.collides(fs, tag.Name) {
+			if name := b.pathFlagName(path, tag.Name); name != "" &&
+				!b.collides(fs, name) {
+				b.OnRename(Rename{tag.Name, name, path})
+				tag.Name, tag.ShortName = name, ""
+			}
+		}
+		if short, ok := b.State.ShortNames[tag.Name]; ok {
+			tag.ShortName = short
+		}
+		if prev, ok := b.State.Flags[tag.Name]; ok {
+			return ErrorDuplicateFlag{tag.Name, path, prev}
+		}
+		for _, alias := range tag.Aliases {
+			alias = b.Prefix + alias
+			if prev, ok := b.State.Flags[alias]; ok {
+				return ErrorDuplicateFlag{alias, path, prev}
+			}
+		}
+		for _, old := range tag.RenamedFrom {
+			old = b.Prefix + old
+			if prev, ok := b.State.Flags[old]; ok {
+				return ErrorDuplicateFlag{old, path, prev}
+			}
+		}
+
+		if b.SkipExisting && flagValue(fs, tag.Name) != nil {
+			err := linkExistingFlag(fs, tag, fieldI, fieldT.Name())
+			if err != nil {
+				return ErrorExistingFlag{tag.Name, path, err}
 			}
 			continue
 		}
 
-		tag.Name = fmt.Sprintf("%v%v", b.Prefix, tag.Name)
+		// Compute a dynamic default if the tag has none.
+		if tag.DefValue != "" && b.ExpandDefaults {
+			tag.DefValue = expandDefault(tag.DefValue)
+		} else if tag.DefValue == "" {
+			defValue, err := dynamicDefault(fieldV.Interface(), tag)
+			if err != nil {
+				return err
+			}
+			tag.DefValue = defValue
+		}
+
+		// Secret references are resolved by SecretLayer, but their
+		// resolver must be registered.
+		if tag.SecretRef != "" {
+			if _, _, err := secretResolver(tag.SecretRef); err != nil {
+				return ErrorSecret{tag.SecretRef, err}
+			}
+		}
 
-		newFlag, err := bindField(fs, tag, fieldI, fieldT.Name())
+		// A value from the ValueSource replaces both the default and
+		// the field value.
+		sourced := false
+		if b.ValueSource != nil {
+			key := tag.Key
+			if key == "" {
+				key = tag.Name
+			}
+			value, ok, err := lookupSource(b.context(),
+				b.ValueSource, key)
+			if err != nil {
+				return ErrorValueSource{key, err}
+			}
+			if ok {
+				tag.DefValue = value
+				sourced = true
+			}
+		}
+
+		// If field value was zero, then parse the tag default, if
+		// specified, into the field before the flag is defined. The
+		// flag is then defined with the default as its value, so the
+		// default is not parsed twice and the flag is not considered
+		// set by the default.
+		isZero := fieldV.Elem().IsZero()
+		hasDefault := (isZero || sourced) && tag.DefValue != "" &&
+			!b.NoDefaults
+		if hasDefault {
+			// A textMapValue is changed by the default, so the
+			// scratch flag gets its own.
+			scratchI := fieldI
+			if m, ok := fieldI.(*textMapValue); ok {
+				scratchI = newTextMapValue(m.value, m.merge)
+			}
+			scratch := b.State.scratch(fs)
+			newFlag, err := defineFlag(scratch, tag, scratchI,
+				fieldT.Name())
+			if err != nil {
+				return err
+			}
+			// Relative times, such as now-24h, are evaluated
+			// now, but are displayed as is.
+			defValue := tag.DefValue
+			if fieldT == timeType {
+				defValue = relativeTime(defValue, time.Now(),
+					tag.Layout)
+			}
+			if newFlag {
+				err := setValue(scratch, tag.Name, defValue)
+				if err != nil {
+					return ErrorDefaultValue{structField.Name,
+						tag.DefValue, err}
+				}
+			}
+		}
+
+		var newFlag bool
+		var err error
+		if b.OverrideExisting && flagValue(fs, tag.Name) != nil {
+			newFlag, err = redefineFlag(fs, tag, fieldI, fieldT.Name())
+		} else {
+			newFlag, err = defineFlag(fs, tag, fieldI, fieldT.Name())
+		}
 		if err != nil {
 			return err
 		}
 		if !newFlag {
 			continue
 		}
+		b.State.addFlag(tag.Name, path)
+		b.State.Tags[tag.Name] = tag
+		// Action funcs are not synchronized, so that they may use
+		// the Values.
+		if b.Values != nil && fieldT.Kind() != reflect.Func {
+			synchronizeFlag(fs, tag.Name, fieldV, b.Values)
+		}
+		if sourced {
+			b.State.addOrigin(tag.Name, OriginConfig)
+		} else if !isZero {
+			b.State.addOrigin(tag.Name, OriginValue)
+		}
 
-		// If field value was zero, then set the tag default, if
-		// specified.
-		if fieldV.Elem().IsZero() && tag.DefValue != "" {
-			defaults[tag.Name] = tag.DefValue
-			if err := fs.Set(tag.Name, tag.DefValue); err != nil {
-				return ErrorDefaultValue{structField.Name, tag.DefValue, err}
+		if hasDefault {
+			defValue := tag.DefValue
+			if _, isSecret := fieldI.(*Secret); isSecret || tag.Secret {
+				defValue = redacted
+			}
+			setDefValue(fs, tag.Name, defValue)
+		}
+		if tag.OnChange != "" {
+			fn, err := onChangeFunc(tag.OnChange)
+			if err != nil {
+				return err
 			}
+			name := tag.Name
+			value := flagValue(fs, name)
+			afterSet(fs, name, func() error {
+				fn(name, value.String())
+				return nil
+			})
+		}
+		if (b.HideZeroDefaults || tag.SkipZeroDefault) &&
+			fieldV.Elem().IsZero() {
+			hideZeroDefault(fs, tag.Name)
+		}
+		if tag.Sensitive {
+			redactFlag(fs, tag.Name, fieldV.Elem().IsZero())
+		}
+		if tag.Deprecated != "" {
+			deprecateFlag(fs, tag.Name, tag.Deprecated)
+		}
+		for _, alias := range tag.Aliases {
+			alias = b.Prefix + alias
+			aliasFlag(fs, tag.Name, alias)
+			b.State.Flags[alias] = path
+		}
+		for _, old := range tag.RenamedFrom {
+			old = b.Prefix + old
+			legacyFlag(fs, tag.Name, old)
+			b.State.Flags[old] = path
 		}
 	}
 
-	return setDefaults(fs, defaults)
+	return nil
+}
+
+// DefaultsSetter is implemented by structs that set their own defaults. Bind
+// calls SetDefaults on `v`, and on each nested struct, before reading any of
+// its fields. Since non-zero field values override the Flag Tag <default>, the
+// defaults set by SetDefaults take precedence, and the tag defaults apply to
+// any fields left zero.
+type DefaultsSetter interface {
+	SetDefaults()
+}
+
+// Defaulter is implemented by field types that compute their own default at
+// bind time. DefaultFlagValue returns the default, which is parsed like a
+// Flag Tag <default>.
+type Defaulter interface {
+	DefaultFlagValue() string
 }
 
-func setDefaults(fs FlagSet, defaults map[string]string) error {
+// dynamicDefault returns the default from the `default-func` tag option, or
+// else from the field pointer p if it implements Defaulter.
+func dynamicDefault(p interface{}, tag flagTag) (string, error) {
+	if tag.DefaultFunc != "" {
+		fn, err := defaultFunc(tag.DefaultFunc)
+		if err != nil {
+			return "", err
+		}
+		return fn(), nil
+	}
+	if d, ok := p.(Defaulter); ok {
+		return d.DefaultFlagValue(), nil
+	}
+	return "", nil
+}
+
+// isValue reports whether the field pointer p is bound as a single flag, so
+// that Bind does not dive into it even if it is a struct.
+func isValue(p interface{}) bool {
+	switch p.(type) {
+	case flag.Value, *json.RawMessage, *url.URL, *os.FileMode,
+		*mail.Address, *net.TCPAddr, *net.UDPAddr, textBidiMarshaler:
+		return true
+	}
+	return false
+}
+
+// defineFlag defines the flag for the field pointer p, with any tag options
+// that wrap its Value.
+func defineFlag(fs FlagSet, tag flagTag, p interface{},
+	typeName string) (bool, error) {
+	newFlag, err := bindField(fs, tag, p, typeName)
+	if err != nil || !newFlag {
+		return newFlag, err
+	}
+
+	if len(tag.Choices) > 0 {
+		transformFlag(fs, tag.Name, checkChoices(tag.Choices))
+	}
+	// Values are normalized before their choices are checked.
+	if len(tag.Normalize) > 0 {
+		normalize, err := normalizer(tag.Normalize)
+		if err != nil {
+			return false, err
+		}
+		transformFlag(fs, tag.Name, normalize)
+	}
+	if tag.ExpandFile {
+		transformFlag(fs, tag.Name, expandFile)
+	}
+	// Environment variables are expanded before any file is read.
+	if tag.ExpandEnv {
+		transformFlag(fs, tag.Name, expandEnv)
+	}
+	return true, nil
+}
+
+// settable returns a settable Value for the addressable struct, or non-nil
+// struct pointer, val obtained through an unexported embedded field. It
+// returns the zero Value if val is a nil pointer, which cannot be allocated.
+func settable(val reflect.Value) reflect.Value {
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		return reflect.NewAt(val.Type().Elem(),
+			unsafe.Pointer(val.Pointer()))
+	}
+	return reflect.NewAt(val.Type(), unsafe.Pointer(val.UnsafeAddr())).Elem()
+}
+
+// newFlagSetLike returns a new, empty FlagSet from the same package as fs.
+func newFlagSetLike(fs FlagSet) FlagSet {
+	if _, ok := fs.(PFlagSet); ok {
+		pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		pfs.SetOutput(ioutil.Discard)
+		return pfs
+	}
+	stdfs := flag.NewFlagSet("", flag.ContinueOnError)
+	stdfs.SetOutput(ioutil.Discard)
+	return stdfs
+}
+
+// flagValue returns the Value of the flag name in fs, or nil if it is not
+// defined.
+func flagValue(fs FlagSet, name string) flag.Value {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		if f := fs.Lookup(name); f != nil {
+			return f.Value
+		}
+	case PFlagSet:
+		if f := fs.Lookup(name); f != nil {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// linkExistingFlag sets the field pointer p to the value of the flag
+// tag.Name, which is already defined in fs, and each time the flag is set
+// afterwards. The flag must have the same type that p would be bound as.
+func linkExistingFlag(fs FlagSet, tag flagTag, p interface{},
+	typeName string) error {
+	scratch := newFlagSetLike(fs)
+	newFlag, err := defineFlag(scratch, tag, p, typeName)
+	if err != nil || !newFlag {
+		return err
+	}
+	field, existing := flagValue(scratch, tag.Name), flagValue(fs, tag.Name)
+	if flagType(field) != flagType(existing) {
+		return fmt.Errorf("type %v does not match %v",
+			flagType(existing), flagType(field))
+	}
+	if err := copyValue(field, existing); err != nil {
+		return err
+	}
+	var text string
+	transformFlag(fs, tag.Name, func(t string) (string, error) {
+		text = t
+		return t, nil
+	})
+	afterSet(fs, tag.Name, func() error { return field.Set(text) })
+	return nil
+}
+
+// redefineFlag replaces the Value, default, and usage, if tag has one, of the
+// flag tag.Name, which is already defined in fs, with those of the flag for the
+// field pointer p, since neither flag package allows a flag to be redefined.
+// Any short name is kept.
+func redefineFlag(fs FlagSet, tag flagTag, p interface{},
+	typeName string) (bool, error) {
+	scratch := newFlagSetLike(fs)
+	newFlag, err := defineFlag(scratch, tag, p, typeName)
+	if err != nil || !newFlag {
+		return newFlag, err
+	}
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f, def := fs.Lookup(tag.Name), scratch.(STDFlagSet).Lookup(tag.Name)
+		f.Value, f.DefValue = def.Value, def.DefValue
+		if tag.Usage != "" {
+			f.Usage = def.Usage
+		}
+	case PFlagSet:
+		f, def := fs.Lookup(tag.Name), scratch.(PFlagSet).Lookup(tag.Name)
+		f.Value, f.DefValue = def.Value, def.DefValue
+		f.NoOptDefVal, f.Hidden = def.NoOptDefVal, def.Hidden
+		if tag.Usage != "" {
+			f.Usage = def.Usage
+		}
+	}
+	return true, nil
+}
+
+// flagType returns the Type of the Value v, or else the name of its type.
+func flagType(v flag.Value) string {
+	v = baseValue(v)
+	if v, ok := v.(interface{ Type() string }); ok {
+		return v.Type()
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// copyValue sets dst to the value of src.
+func copyValue(dst, src flag.Value) error {
+	if src, ok := baseValue(src).(pflag.SliceValue); ok {
+		if dst, ok := baseValue(dst).(pflag.SliceValue); ok {
+			return dst.Replace(src.GetSlice())
+		}
+	}
+	return dst.Set(baseValue(src).String())
+}
+
+// setValue calls Set on the Value of the flag name, so that, unlike
+// pflag.FlagSet.Set, the error is returned as is.
+func setValue(fs FlagSet, name, text string) error {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		return fs.Lookup(name).Value.Set(text)
+	case PFlagSet:
+		return fs.Lookup(name).Value.Set(text)
+	}
+	return fs.Set(name, text)
+}
+
+// setDefValue sets the default value shown in the usage for the flag name.
+func setDefValue(fs FlagSet, name, defValue string) {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		fs.Lookup(name).DefValue = defValue
+	case PFlagSet:
+		fs.Lookup(name).DefValue = defValue
+	}
+}
+
+// aliasFlag defines the flag alias that sets the Value of the flag name. With
+// pflag, the alias is hidden.
+func aliasFlag(fs FlagSet, name, alias string) {
+	usage := fmt.Sprintf("Alias for %v", name)
+	value := aliasValue{fs, name}
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		fs.Var(value, alias, usage)
+		fs.Lookup(alias).DefValue = f.DefValue
+	case PFlagSet:
+		f := fs.Lookup(name)
+		a := fs.VarPF(value, alias, "", usage)
+		a.DefValue = f.DefValue
+		a.NoOptDefVal = f.NoOptDefVal
+		a.Hidden = true
+	}
+}
+
+// aliasValue is the Value of an alias of the flag name. It uses the current
+// Value of the flag, so that any wrappers added to it after the alias was
+// defined still apply, and so that setFlags may report the flag as set.
+type aliasValue struct {
+	fs   FlagSet
+	name string
+}
+
+func (v aliasValue) Set(text string) error {
+	return flagValue(v.fs, v.name).Set(text)
+}
+
+func (v aliasValue) String() string {
+	if v.fs == nil {
+		return ""
+	}
+	return flagValue(v.fs, v.name).String()
+}
+
+func (v aliasValue) Type() string {
+	if v, ok := flagValue(v.fs, v.name).(interface{ Type() string }); ok {
+		return v.Type()
+	}
+	return ""
+}
+
+func (v aliasValue) IsBoolFlag() bool {
+	b, ok := flagValue(v.fs, v.name).(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// aliasOf returns the name of the flag that v is an alias of, if any.
+func aliasOf(v flag.Value) (string, bool) {
+	for v != nil {
+		if v, ok := v.(aliasValue); ok {
+			return v.name, true
+		}
+		v = unwrapValue(v)
+	}
+	return "", false
+}
+
+// deprecateFlag marks the flag name as deprecated with the message msg. With
+// pflag, the flag is hidden and pflag prints the message when it is used. With
+// the standard flag package, the message is appended to the usage and printed
+// to the Output of fs, if it is a *flag.FlagSet, or else os.Stderr.
+func deprecateFlag(fs FlagSet, name, msg string) {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		f.Usage = strings.TrimSpace(f.Usage + " (deprecated: " + msg + ")")
+		var out io.Writer = os.Stderr
+		if fs, ok := fs.(interface{ Output() io.Writer }); ok {
+			out = fs.Output()
+		}
+		transformFlag(fs, name, func(text string) (string, error) {
+			fmt.Fprintf(out, "Flag -%s has been deprecated, %s\n",
+				name, msg)
+			return text, nil
+		})
+	case PFlagSet:
+		f := fs.Lookup(name)
+		f.Deprecated = msg
+		f.Hidden = true
+	}
+}
+
+// flagNames returns the names of all flags defined in fs.
+func flagNames(fs FlagSet) []string {
+	var names []string
 	switch fs := fs.(type) {
 	case STDFlagSet:
 		fs.VisitAll(func(f *flag.Flag) {
-			defVal, ok := defaults[f.Name]
-			if !ok {
-				return
-			}
-			f.DefValue = defVal
+			names = append(names, f.Name)
 		})
 	case PFlagSet:
 		fs.VisitAll(func(f *pflag.Flag) {
-			defVal, ok := defaults[f.Name]
-			if !ok {
-				return
-			}
-			f.DefValue = defVal
+			names = append(names, f.Name)
 		})
-	default:
-		return ErrorInvalidFlagSet
 	}
-	return nil
-
+	return names
 }
 
 func loadExtendedUsage(i int, valT reflect.Type, tag *flagTag) int {
@@ -503,23 +1393,104 @@ func loadExtendedUsage(i int, valT reflect.Type, tag *flagTag) int {
 	return i
 }
 
-func appendSeparator(prefix string) string {
+// flagName derives a flag name from the field name using the NameFunc, or
+// else the Splitter and Separator. The words split by the default
+// CamelCaseSplitter are cached.
+func (b bind) flagName(field cachedField) string {
+	if name := b.tagName(field.StructField); name != "" {
+		return name
+	}
+	if b.NameFunc != nil {
+		return b.NameFunc(field.Name)
+	}
+	words := field.Words
+	if b.Splitter != nil {
+		words = b.Splitter.Split(field.Name)
+	}
+	return strings.Join(words, b.separator())
+}
+
+// tagName returns the `json` tag name of field, if JSONNames is set, or else
+// the flag name derived from the first of the NameTags set on field, or "" if
+// none are set. Only the name before any comma is used, and
+// it is split into words at any character that is not a letter or digit and
+// at any change of case, so that "listen_addr", "LISTEN_ADDR", and
+// "listenAddr" all become "listen-addr".
+func (b bind) tagName(field reflect.StructField) string {
+	if b.JSONNames {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	for _, key := range b.NameTags {
+		val := strings.Split(field.Tag.Get(key), ",")[0]
+		if val == "" || val == "-" {
+			continue
+		}
+		var words []string
+		for _, part := range strings.FieldsFunc(val, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			words = append(words, CamelCaseSplitter{}.Split(part)...)
+		}
+		return strings.Join(words, b.separator())
+	}
+	return ""
+}
+
+// nestedPrefix returns the prefix for the fields of a nested struct, or map or
+// slice of structs, with the given tag, which is prefix followed by each of
+// names. The names are separated by the `sep` tag option, if any, or else
+// joined by joinPrefix.
+func (b bind) nestedPrefix(prefix string, tag flagTag, names ...string) string {
+	for _, name := range names {
+		if tag.HasSep {
+			prefix += name + tag.Sep
+			continue
+		}
+		prefix = b.joinPrefix(prefix, name)
+	}
+	return prefix
+}
+
+// joinPrefix returns prefix + name with the separator appended, in a single
+// allocation.
+func (b bind) joinPrefix(prefix, name string) string {
 	// Do not append separator to an empty prefix.
-	if prefix == "" {
-		return prefix
+	if prefix == "" && name == "" {
+		return ""
+	}
+	last := name
+	if last == "" {
+		last = prefix
 	}
 
 	// Do not append separator when other common separators are being used.
-	for _, sep := range []string{"-", ".", "_"} {
-		if strings.HasSuffix(prefix, sep) {
-			return prefix
+	sep := b.separator()
+	for _, common := range []string{"-", ".", "_"} {
+		if strings.HasSuffix(last, common) {
+			if name == "" {
+				return prefix
+			}
+			sep = ""
+			break
 		}
 	}
 
-	return prefix + Separator
+	var sb strings.Builder
+	sb.Grow(len(prefix) + len(name) + len(sep))
+	sb.WriteString(prefix)
+	sb.WriteString(name)
+	sb.WriteString(sep)
+	return sb.String()
 }
 
 func bindField(fs FlagSet, tag flagTag, p interface{}, typeName string) (bool, error) {
+	if _, ok := p.(*[]byte); ok && !validEncoding(tag.Encoding) {
+		return false, ErrorTagOption{tag.Name, "encoding=" + tag.Encoding}
+	}
+
 	switch fs := fs.(type) {
 	case STDFlagSet:
 		return bindSTDFlag(fs, tag, p), nil
@@ -532,24 +1503,76 @@ func bindField(fs FlagSet, tag flagTag, p interface{}, typeName string) (bool, e
 
 func bindSTDFlag(fs STDFlagSet, tag flagTag, p interface{}) bool {
 	switch p := p.(type) {
+	case *File:
+		// File and Dir are flag.Value, but its tag options may add checks.
+		fs.Var(newFileValue(p, tag), tag.Name, tag.Usage)
+	case *Dir:
+		fs.Var(newDirValue(p, tag), tag.Name, tag.Usage)
 	case flag.Value:
 		fs.Var(p, tag.Name, tag.Usage)
 	case *json.RawMessage:
+		if tag.Merge {
+			fs.Var((*mergeJSONRawMessage)(p), tag.Name, tag.Usage)
+			break
+		}
 		fs.Var((*JSONRawMessage)(p), tag.Name, tag.Usage)
 	case *url.URL:
-		fs.Var((*URL)(p), tag.Name, tag.Usage)
+		fs.Var(newURLValue(p, tag), tag.Name, tag.Usage)
+	case *os.FileMode:
+		fs.Var((*FileMode)(p), tag.Name, tag.Usage)
+	case *mail.Address:
+		fs.Var((*MailAddress)(p), tag.Name, tag.Usage)
+	case *net.TCPAddr:
+		fs.Var((*TCPAddr)(p), tag.Name, tag.Usage)
+	case *net.UDPAddr:
+		fs.Var((*UDPAddr)(p), tag.Name, tag.Usage)
+	case *[]byte:
+		if tag.Encoding == "base64" {
+			fs.Var((*BytesBase64)(p), tag.Name, tag.Usage)
+			break
+		}
+		fs.Var((*BytesHex)(p), tag.Name, tag.Usage)
+	case *map[string]string:
+		fs.Var(&stringToStringValue{value: p, merge: tag.Merge},
+			tag.Name, tag.Usage)
+	case **time.Location:
+		fs.Var(&locationValue{p}, tag.Name, tag.Usage)
+	case *time.Time:
+		fs.Var(newTimeValue(p, tag.Layout), tag.Name, tag.Usage)
+	case *[]time.Time:
+		fs.Var(newTimeSliceValue(p, tag.Layout), tag.Name, tag.Usage)
+	case *func(string) error:
+		if *p == nil {
+			return false
+		}
+		fs.Var(funcValue{p}, tag.Name, tag.Usage)
+	case *func() error:
+		if *p == nil {
+			return false
+		}
+		fs.Var(boolFuncValue{p}, tag.Name, tag.Usage)
 	case *bool:
 		val := *p
 		fs.BoolVar(p, tag.Name, val, tag.Usage)
 	case *time.Duration:
+		if tag.ExtendedDuration {
+			fs.Var((*durationValue)(p), tag.Name, tag.Usage)
+			break
+		}
 		val := *p
 		fs.DurationVar(p, tag.Name, val, tag.Usage)
 	case *int:
+		if tag.Count {
+			fs.Var((*countValue)(p), tag.Name, tag.Usage)
+			break
+		}
 		val := *p
 		fs.IntVar(p, tag.Name, val, tag.Usage)
 	case *uint:
 		val := *p
 		fs.UintVar(p, tag.Name, val, tag.Usage)
+	case *int8, *int16, *int32, *uint8, *uint16, *uint32:
+		fs.Var(newSizedIntValue(p), tag.Name, tag.Usage)
 	case *int64:
 		val := *p
 		fs.Int64Var(p, tag.Name, val, tag.Usage)
@@ -560,6 +1583,10 @@ func bindSTDFlag(fs STDFlagSet, tag flagTag, p interface{}) bool {
 		val := *p
 		fs.Float64Var(p, tag.Name, val, tag.Usage)
 	case *string:
+		if tag.Secret {
+			fs.Var((*Secret)(p), tag.Name, tag.Usage)
+			break
+		}
 		val := *p
 		fs.StringVar(p, tag.Name, val, tag.Usage)
 	case textBidiMarshaler:
@@ -583,6 +1610,13 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 
 	var f *pflag.Flag
 	switch p := p.(type) {
+	case *File:
+		// File and Dir are flag.Value, but its tag options may add checks.
+		f = fs.VarPF(newFileValue(p, tag),
+			tag.Name, tag.ShortName, tag.Usage)
+	case *Dir:
+		f = fs.VarPF(newDirValue(p, tag),
+			tag.Name, tag.ShortName, tag.Usage)
 	case flag.Value:
 		// Check if p also implements pflag.Value...
 		pp, ok := p.(pflag.Value)
@@ -592,9 +1626,37 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 		}
 		f = fs.VarPF(pp, tag.Name, tag.ShortName, tag.Usage)
 	case *json.RawMessage:
+		if tag.Merge {
+			f = fs.VarPF((*mergeJSONRawMessage)(p),
+				tag.Name, tag.ShortName, tag.Usage)
+			break
+		}
 		f = fs.VarPF((*JSONRawMessage)(p), tag.Name, tag.ShortName, tag.Usage)
 	case *url.URL:
-		f = fs.VarPF((*URL)(p), tag.Name, tag.ShortName, tag.Usage)
+		f = fs.VarPF(newURLValue(p, tag), tag.Name, tag.ShortName, tag.Usage)
+	case *os.FileMode:
+		f = fs.VarPF((*FileMode)(p), tag.Name, tag.ShortName, tag.Usage)
+	case *mail.Address:
+		f = fs.VarPF((*MailAddress)(p), tag.Name, tag.ShortName, tag.Usage)
+	case *net.TCPAddr:
+		f = fs.VarPF((*TCPAddr)(p), tag.Name, tag.ShortName, tag.Usage)
+	case *net.UDPAddr:
+		f = fs.VarPF((*UDPAddr)(p), tag.Name, tag.ShortName, tag.Usage)
+	case *[]byte:
+		val := *p
+		if tag.Encoding == "base64" {
+			fs.BytesBase64VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+			break
+		}
+		fs.BytesHexVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case **time.Location:
+		f = fs.VarPF(&locationValue{p}, tag.Name, tag.ShortName, tag.Usage)
+	case *time.Time:
+		f = fs.VarPF(newTimeValue(p, tag.Layout),
+			tag.Name, tag.ShortName, tag.Usage)
+	case *[]time.Time:
+		f = fs.VarPF(newTimeSliceValue(p, tag.Layout),
+			tag.Name, tag.ShortName, tag.Usage)
 	case *net.IP:
 		val := *p
 		fs.IPVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
@@ -604,10 +1666,26 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 	case *bool:
 		val := *p
 		fs.BoolVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *func(string) error:
+		if *p == nil {
+			return false
+		}
+		f = fs.VarPF(funcValue{p}, tag.Name, tag.ShortName, tag.Usage)
+	case *func() error:
+		if *p == nil {
+			return false
+		}
+		f = fs.VarPF(boolFuncValue{p}, tag.Name, tag.ShortName, tag.Usage)
+		f.NoOptDefVal = "true"
 	case *[]bool:
 		val := *p
 		fs.BoolSliceVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *time.Duration:
+		if tag.ExtendedDuration {
+			f = fs.VarPF((*durationValue)(p),
+				tag.Name, tag.ShortName, tag.Usage)
+			break
+		}
 		val := *p
 		fs.DurationVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *[]time.Duration:
@@ -615,6 +1693,14 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 		fs.DurationSliceVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *int:
 		val := *p
+		if tag.Count {
+			// CountVarP always zeros p, so restore any default.
+			fs.CountVarP(p, tag.Name, tag.ShortName, tag.Usage)
+			f = fs.Lookup(tag.Name)
+			*p = val
+			f.DefValue = f.Value.String()
+			break
+		}
 		fs.IntVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *[]int:
 		val := *p
@@ -625,6 +1711,24 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 	case *[]uint:
 		val := *p
 		fs.UintSliceVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *int8:
+		val := *p
+		fs.Int8VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *int16:
+		val := *p
+		fs.Int16VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *int32:
+		val := *p
+		fs.Int32VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *uint8:
+		val := *p
+		fs.Uint8VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *uint16:
+		val := *p
+		fs.Uint16VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *uint32:
+		val := *p
+		fs.Uint32VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *int64:
 		val := *p
 		fs.Int64VarP(p, tag.Name, tag.ShortName, val, tag.Usage)
@@ -647,11 +1751,23 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 		val := *p
 		fs.Float64SliceVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *string:
+		if tag.Secret {
+			f = fs.VarPF((*Secret)(p), tag.Name, tag.ShortName, tag.Usage)
+			break
+		}
 		val := *p
 		fs.StringVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
 	case *[]string:
 		val := *p
+		if tag.Array {
+			fs.StringArrayVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+			break
+		}
 		fs.StringSliceVarP(p, tag.Name, tag.ShortName, val, tag.Usage)
+	case *map[string]string:
+		// Use our StringToString so that Resolve may reset it.
+		f = fs.VarPF(&stringToStringValue{value: p, merge: tag.Merge},
+			tag.Name, tag.ShortName, tag.Usage)
 	case textBidiMarshaler:
 		// Match the interface after concrete types so that any concrete types that
 		// also implement the interface use the more specific implementation for
@@ -678,6 +1794,27 @@ func bindPFlag(fs PFlagSet, tag flagTag, p interface{}, typeName string) bool {
 }
 
 func overrideFlag(fs FlagSet, tag flagTag) error {
+	// A name ending in "*" overrides every flag with the preceding prefix.
+	if strings.HasSuffix(tag.Name, "*") {
+		prefix := strings.TrimSuffix(tag.Name, "*")
+		tag.ShortName = ""
+		var found bool
+		for _, name := range flagNames(fs) {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			found = true
+			tag.Name = name
+			if err := overrideFlag(fs, tag); err != nil {
+				return err
+			}
+		}
+		if !found {
+			return ErrorFlagOverrideUndefined{prefix + "*"}
+		}
+		return nil
+	}
+
 	// Update flag if it exists.
 	switch fs := fs.(type) {
 	case STDFlagSet:
@@ -706,6 +1843,9 @@ func overrideSTDFlag(fs STDFlagSet, tag flagTag) error {
 	if tag.HideDefault {
 		f.DefValue = ""
 	}
+	if tag.Deprecated != "" {
+		deprecateFlag(fs, tag.Name, tag.Deprecated)
+	}
 
 	return nil
 }
@@ -726,7 +1866,16 @@ func overridePFlag(fs PFlagSet, tag flagTag) error {
 	if tag.HideDefault {
 		f.DefValue = ""
 	}
-	f.Hidden = tag.Hidden
+	if tag.Hidden {
+		f.Hidden = true
+	}
+	if tag.Deprecated != "" {
+		f.Deprecated = tag.Deprecated
+		f.Hidden = true
+	}
+	if isOverrideShortName(tag) && f.Shorthand != tag.ShortName {
+		return ErrorFlagOverrideShortName{tag.Name, tag.ShortName}
+	}
 
 	return nil
 }
@@ -22,14 +22,20 @@ package flagbind
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/mail"
 	"net/url"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -232,6 +238,9 @@ type StructA struct {
 type StructB struct {
 	StructBBool bool
 }
+type StructC struct {
+	A, B string
+}
 
 func TestBind(t *testing.T) {
 	for _, test := range tests {
@@ -379,6 +388,23 @@ var tests = []BindTest{
 		},
 		ErrParse:      "flag provided but not defined: -lg",
 		ErrPFlagParse: "unknown flag: --lg",
+	}, {
+		Name: "StrictShortNames invalid short name",
+		Opts: []Option{StrictShortNames()},
+		F: &struct {
+			E bool `flag:"lg,long"`
+		}{},
+		ErrBind: ErrorShortName{"E", "lg"}.Error(),
+	}, {
+		Name: "StrictShortNames",
+		Opts: []Option{StrictShortNames()},
+		F: &struct {
+			E bool `flag:"e"`
+		}{},
+		ParseArgs: []string{"-e"},
+		ExpF: &struct {
+			E bool `flag:"e"`
+		}{true},
 	}, {
 		Name: "valid JSON",
 		F: &struct {
@@ -401,20 +427,21 @@ var tests = []BindTest{
 		ErrParse:      "invalid value \"asdf{\\\"hello\\\":\\\"world\\\"}\" for flag -json: invalid character 'a' looking for beginning of value",
 		ErrPFlagParse: "invalid argument \"asdf{\\\"hello\\\":\\\"world\\\"}\" for \"--json\" flag: invalid character 'a' looking for beginning of value",
 	}, {
-		Name: "ErrorNestedStruct",
+		Name: "nested ErrorDefaultValue",
 		F: &struct {
 			E struct {
 				Value TestValue `flag:";asdf;"`
 			}
 		}{},
-		ErrBind: ErrorNestedStruct{"E",
-			ErrorDefaultValue{"Value", "asdf", nil}}.Error(),
+		ErrBind: `E.Value: cannot assign default value "asdf": ` +
+			`could not parse "asdf" as TestValue`,
 	}, {
 		Name: "ErrorDefaultValue",
 		F: &struct {
 			Value TestValue `flag:";asdf;"`
 		}{},
-		ErrBind: ErrorDefaultValue{"Value", "asdf", nil}.Error(),
+		ErrBind: `Value: cannot assign default value "asdf": ` +
+			`could not parse "asdf" as TestValue`,
 	}, {
 		Name: "ErrorFlagOverrideUndefined",
 		F: &struct {
@@ -428,7 +455,531 @@ var tests = []BindTest{
 			Duplicate  bool
 			Duplicate_ bool `flag:"duplicate"`
 		}{},
-		ErrBind: fmt.Errorf("flag redefined: %v", "duplicate").Error(),
+		ErrBind: ErrorDuplicateFlag{"duplicate", "Duplicate_",
+			"Duplicate"}.Error(),
+	}, {
+		Name: "Duplicate nested Flag name",
+		F: &struct {
+			A struct{ Timeout int } `flag:";;;flatten"`
+			B struct {
+				Client struct{ Timeout int } `flag:";;;flatten"`
+			} `flag:";;;flatten"`
+		}{},
+		ErrBind: `A.Timeout and B.Client.Timeout both map to "timeout"`,
+	}, {
+		Name: "ErrorAliasedField",
+		F: func() interface{} {
+			shared := &StructA{}
+			return &struct {
+				A *StructA
+				B struct{ Shared *StructA }
+			}{A: shared, B: struct{ Shared *StructA }{shared}}
+		}(),
+		ErrBind: ErrorNestedStruct{"B",
+			ErrorAliasedField{"Shared", "A"}}.Error(),
+	}, {
+		Name: "FileMode",
+		F: &struct {
+			Mode    os.FileMode `flag:";0644"`
+			DirMode os.FileMode `flag:";0755"`
+		}{},
+		ParseArgs: []string{
+			"-mode", "0600",
+		},
+		ExpF: &struct {
+			Mode    os.FileMode `flag:";0644"`
+			DirMode os.FileMode `flag:";0755"`
+		}{0600, 0755},
+		UsageContains: []string{"0755"},
+	}, {
+		Name: "FileMode invalid",
+		F: &struct {
+			Mode os.FileMode
+		}{},
+		ParseArgs: []string{
+			"-mode", "0800",
+		},
+		ErrParse:      `invalid value "0800" for flag -mode: invalid octal file mode "0800"`,
+		ErrPFlagParse: `invalid argument "0800" for "--mode" flag: invalid octal file mode "0800"`,
+	}, {
+		Name: "[]byte",
+		F: &struct {
+			Key  []byte `flag:";00ff"`
+			Salt []byte `flag:";;;encoding=base64"`
+		}{},
+		ParseArgs: []string{
+			"-salt", "c2FsdA==",
+		},
+		ExpF: &struct {
+			Key  []byte `flag:";00ff"`
+			Salt []byte `flag:";;;encoding=base64"`
+		}{[]byte{0x00, 0xff}, []byte("salt")},
+	}, {
+		Name: "[]byte invalid encoding",
+		F: &struct {
+			Key []byte `flag:";;;encoding=base32"`
+		}{},
+		ErrBind: ErrorTagOption{"key", "encoding=base32"}.Error(),
+	}, {
+		Name: "unknown tag option",
+		F: &struct {
+			Nested struct {
+				Value int `flag:";;;hidden,hide-defualt"`
+			}
+		}{},
+		ErrBind: ErrorNestedStruct{"Nested", ErrorTagOption{
+			"nested-value", "hide-defualt"}}.Error(),
+	}, {
+		Name: "unknown override tag option",
+		F: &struct {
+			Value int
+			_     struct{} `flag:"value;;;hidden-thing"`
+		}{},
+		ErrBind: ErrorTagOption{"value", "hidden-thing"}.Error(),
+	}, {
+		Name: "invalid len tag option",
+		F: &struct {
+			Servers []struct{ Host string } `flag:";;;len=-1"`
+		}{},
+		ErrBind: ErrorTagOption{"servers", "len=-1"}.Error(),
+	}, {
+		Name: "misspelled option after a list",
+		F: &struct {
+			Format string `flag:";;;choices=a|b,hiden"`
+		}{},
+		ErrBind: ErrorTagOption{"format", "hiden"}.Error(),
+	}, {
+		Name: "empty list element",
+		F: &struct {
+			Format string `flag:";;;choices=a||b"`
+		}{},
+		ErrBind: ErrorTagOption{"format", "choices=a||b"}.Error(),
+	}, {
+		Name: "invalid precedence tag option",
+		F: &struct {
+			Token string `flag:";;;precedence=env|file"`
+		}{},
+		ErrBind: ErrorTagOption{"token", "precedence=env|file"}.Error(),
+	}, {
+		Name: "map[string]string",
+		F: &struct {
+			Label map[string]string
+		}{},
+		ParseArgs: []string{
+			"-label", "env=prod",
+			"-label", "team=core,tier=1",
+		},
+		ExpF: &struct {
+			Label map[string]string
+		}{map[string]string{"env": "prod", "team": "core", "tier": "1"}},
+	}, {
+		Name: "map[string]string invalid",
+		F: &struct {
+			Label map[string]string
+		}{},
+		ParseArgs: []string{
+			"-label", "env",
+		},
+		ErrParse:      `invalid value "env" for flag -label: env must be formatted as key=value`,
+		ErrPFlagParse: `invalid argument "env" for "--label" flag: env must be formatted as key=value`,
+	}, {
+		Name: "Interval",
+		F: &struct {
+			Poll  Interval `flag:";1m"`
+			Retry Interval
+		}{},
+		ParseArgs: []string{
+			"-retry", "30s±5s",
+		},
+		ExpF: &struct {
+			Poll  Interval `flag:";1m"`
+			Retry Interval
+		}{Interval{time.Minute, 0}, Interval{30 * time.Second, 5 * time.Second}},
+	}, {
+		Name: "Interval negative jitter",
+		F: &struct {
+			Retry Interval
+		}{},
+		ParseArgs: []string{
+			"-retry", "30s+--5s",
+		},
+		ErrParse:      `invalid value "30s+--5s" for flag -retry: jitter must not be negative`,
+		ErrPFlagParse: `invalid argument "30s+--5s" for "--retry" flag: jitter must not be negative`,
+	}, {
+		Name: "map[string]TextUnmarshaler",
+		F: &struct {
+			Levels map[string]TestTextMarshaler
+		}{},
+		ParseArgs: []string{
+			"-levels", "db=debug",
+			"-levels", "http=warn=1",
+		},
+		ExpF: &struct {
+			Levels map[string]TestTextMarshaler
+		}{map[string]TestTextMarshaler{
+			"db":   {v: "debug"},
+			"http": {v: "warn=1"},
+		}},
+	}, {
+		Name: "map[string]TextUnmarshaler default",
+		F: &struct {
+			Hosts map[string]net.IP `flag:";a=1.1.1.1"`
+		}{},
+		ParseArgs: []string{
+			"-hosts", "b=2.2.2.2",
+		},
+		ExpF: &struct {
+			Hosts map[string]net.IP `flag:";a=1.1.1.1"`
+		}{map[string]net.IP{"b": net.ParseIP("2.2.2.2")}},
+	}, {
+		Name: "map[string]TextUnmarshaler invalid",
+		F: &struct {
+			Levels map[string]TestTextMarshaler
+		}{},
+		ParseArgs: []string{
+			"-levels", "db",
+		},
+		ErrParse:      `invalid value "db" for flag -levels: db must be formatted as key=value`,
+		ErrPFlagParse: `invalid argument "db" for "--levels" flag: db must be formatted as key=value`,
+	}, {
+		Name: "inline-json",
+		F: &struct {
+			Nested StructA `flag:"sub;;;inline-json"`
+			Other  StructB
+		}{},
+		ParseArgs: []string{
+			"-sub", `{"StructABool":true}`,
+			"-other-struct-b-bool",
+		},
+		ExpF: &struct {
+			Nested StructA `flag:"sub;;;inline-json"`
+			Other  StructB
+		}{StructA{StructABool: true}, StructB{true}},
+	}, {
+		Name: "inline-json invalid",
+		F: &struct {
+			Nested StructA `flag:"sub;;;inline-json"`
+		}{},
+		ParseArgs: []string{
+			"-sub", `{`,
+		},
+		ErrParse:      `invalid value "{" for flag -sub: unexpected end of JSON input`,
+		ErrPFlagParse: `invalid argument "{" for "--sub" flag: unexpected end of JSON input`,
+	}, {
+		Name: "json",
+		F: &struct {
+			Servers []StructC          `flag:";;;json"`
+			Limits  map[string]float64 `flag:";{\"cpu\":1.5};;json"`
+			Numbers []int              `flag:";;;json"`
+		}{},
+		ParseArgs: []string{
+			"-servers", `[{"A":"a"},{"B":"b"}]`,
+			"-numbers", `[1,2,3]`,
+		},
+		ExpF: &struct {
+			Servers []StructC          `flag:";;;json"`
+			Limits  map[string]float64 `flag:";{\"cpu\":1.5};;json"`
+			Numbers []int              `flag:";;;json"`
+		}{
+			Servers: []StructC{{A: "a"}, {B: "b"}},
+			Limits:  map[string]float64{"cpu": 1.5},
+			Numbers: []int{1, 2, 3},
+		},
+	}, {
+		Name: "merge",
+		F: &struct {
+			Labels   map[string]string            `flag:";;;merge"`
+			Levels   map[string]TestTextMarshaler `flag:";;;merge"`
+			Settings json.RawMessage              `flag:";;;merge"`
+			Nested   StructC                      `flag:";;;inline-json,merge"`
+		}{
+			Labels:   map[string]string{"env": "dev"},
+			Levels:   map[string]TestTextMarshaler{"db": {v: "info"}},
+			Settings: json.RawMessage(`{"a":{"x":1}}`),
+			Nested:   StructC{A: "a"},
+		},
+		ParseArgs: []string{
+			"-labels", "env=prod",
+			"-labels", "team=core",
+			"-levels", "http=warn",
+			"-settings", `{"a":{"y":2}}`,
+			"-settings", `{"b":3}`,
+			"-nested", `{"B":"b"}`,
+		},
+		ExpF: &struct {
+			Labels   map[string]string            `flag:";;;merge"`
+			Levels   map[string]TestTextMarshaler `flag:";;;merge"`
+			Settings json.RawMessage              `flag:";;;merge"`
+			Nested   StructC                      `flag:";;;inline-json,merge"`
+		}{
+			Labels: map[string]string{"env": "prod", "team": "core"},
+			Levels: map[string]TestTextMarshaler{
+				"db": {v: "info"}, "http": {v: "warn"}},
+			Settings: json.RawMessage(`{"a":{"x":1,"y":2},"b":3}`),
+			Nested:   StructC{A: "a", B: "b"},
+		},
+	}, {
+		Name: "small integers",
+		F: &struct {
+			Int8   int8
+			Int16  int16 `flag:";-300"`
+			Int32  int32
+			Uint8  uint8
+			Uint16 uint16 `flag:";8080"`
+			Uint32 uint32
+		}{},
+		ParseArgs: []string{
+			"-int8=-128",
+			"-int32", "70000",
+			"-uint8", "255",
+			"-uint32", "4294967295",
+		},
+		ExpF: &struct {
+			Int8   int8
+			Int16  int16 `flag:";-300"`
+			Int32  int32
+			Uint8  uint8
+			Uint16 uint16 `flag:";8080"`
+			Uint32 uint32
+		}{-128, -300, 70000, 255, 8080, 4294967295},
+		UsageContains: []string{"8080"},
+	}, {
+		Name: "small integer out of range",
+		F: &struct {
+			Port uint16
+		}{},
+		ParseArgs: []string{
+			"-port", "65536",
+		},
+		ErrParse:      `invalid value "65536" for flag -port: strconv.ParseUint: parsing "65536": value out of range`,
+		ErrPFlagParse: `invalid argument "65536" for "--port" flag: strconv.ParseUint: parsing "65536": value out of range`,
+	}, {
+		Name: "time.Time",
+		F: &struct {
+			At  []time.Time
+			Day time.Time `flag:";;;layout=DateOnly"`
+			Now time.Time
+		}{},
+		ParseArgs: []string{
+			"-at", "2024-01-01T00:00:00Z",
+			"-at", "2024-02-01T00:00:00Z,2024-03-01T00:00:00Z",
+			"-day", "2024-03-04",
+			"-now", "2024-03-04T05:06:07.5Z",
+		},
+		ExpF: &struct {
+			At  []time.Time
+			Day time.Time `flag:";;;layout=DateOnly"`
+			Now time.Time
+		}{
+			At: []time.Time{
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			},
+			Day: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			Now: time.Date(2024, 3, 4, 5, 6, 7, 5e8, time.UTC),
+		},
+	}, {
+		Name: "[]time.Time layout with comma",
+		F: &struct {
+			At []time.Time `flag:";;;layout=RFC1123"`
+		}{},
+		ParseArgs: []string{
+			"-at", "Mon, 01 Jan 2024 00:00:00 UTC",
+			"-at", "Thu, 01 Feb 2024 00:00:00 UTC",
+		},
+		ExpF: &struct {
+			At []time.Time `flag:";;;layout=RFC1123"`
+		}{
+			At: []time.Time{
+				time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}, {
+		Name: "time.Time invalid",
+		F: &struct {
+			Day time.Time `flag:";;;layout=DateOnly"`
+		}{},
+		ParseArgs: []string{
+			"-day", "2024-03-04T00:00:00Z",
+		},
+		ErrParse:      `invalid value "2024-03-04T00:00:00Z" for flag -day: parsing time "2024-03-04T00:00:00Z": extra text: "T00:00:00Z"`,
+		ErrPFlagParse: `invalid argument "2024-03-04T00:00:00Z" for "--day" flag: parsing time "2024-03-04T00:00:00Z": extra text: "T00:00:00Z"`,
+	}, {
+		Name: "Size",
+		F: &struct {
+			Cache  Size `flag:";512M"`
+			Buffer Size
+		}{},
+		UsageContains: []string{"512M"},
+		ParseArgs: []string{
+			"-buffer", "1.5GB",
+		},
+		ExpF: &struct {
+			Cache  Size `flag:";512M"`
+			Buffer Size
+		}{512 << 20, 1.5e9},
+	}, {
+		Name: "Percent",
+		F: &struct {
+			Sample    Percent `flag:";10%"`
+			Threshold Percent
+			Rate      Percent
+		}{Rate: 0.05},
+		UsageContains: []string{"10%", "5%"},
+		ParseArgs: []string{
+			"-threshold", "85%",
+		},
+		ExpF: &struct {
+			Sample    Percent `flag:";10%"`
+			Threshold Percent
+			Rate      Percent
+		}{0.1, 0.85, 0.05},
+	}, {
+		Name: "Percent out of range",
+		F: &struct {
+			Sample Percent
+		}{},
+		ParseArgs: []string{
+			"-sample", "85",
+		},
+		ErrParse:      `invalid value "85" for flag -sample: percent "85" is not within 0% and 100%`,
+		ErrPFlagParse: `invalid argument "85" for "--sample" flag: percent "85" is not within 0% and 100%`,
+	}, {
+		Name: "Percent NaN",
+		F: &struct {
+			Sample Percent
+		}{},
+		ParseArgs: []string{
+			"-sample", "NaN%",
+		},
+		ErrParse:      `invalid value "NaN%" for flag -sample: invalid percent "NaN%"`,
+		ErrPFlagParse: `invalid argument "NaN%" for "--sample" flag: invalid percent "NaN%"`,
+	}, {
+		Name: "Percent Inf",
+		F: &struct {
+			Sample Percent
+		}{},
+		ParseArgs: []string{
+			"-sample", "+Inf",
+		},
+		ErrParse:      `invalid value "+Inf" for flag -sample: invalid percent "+Inf"`,
+		ErrPFlagParse: `invalid argument "+Inf" for "--sample" flag: invalid percent "+Inf"`,
+	}, {
+		Name: "time.Location",
+		F: &struct {
+			TZ      *time.Location
+			Default *time.Location `flag:";UTC"`
+			Unset   *time.Location
+		}{},
+		ParseArgs: []string{
+			"-tz", "America/New_York",
+		},
+		ExpF: &struct {
+			TZ      *time.Location
+			Default *time.Location `flag:";UTC"`
+			Unset   *time.Location
+		}{mustLoadLocation("America/New_York"), time.UTC, nil},
+	}, {
+		Name: "time.Location invalid",
+		F: &struct {
+			TZ *time.Location
+		}{},
+		ParseArgs: []string{
+			"-tz", "Nowhere/Special",
+		},
+		ErrParse:      `invalid value "Nowhere/Special" for flag -tz: unknown time zone Nowhere/Special`,
+		ErrPFlagParse: `invalid argument "Nowhere/Special" for "--tz" flag: unknown time zone Nowhere/Special`,
+	}, {
+		Name: "URL validation",
+		F: &struct {
+			Endpoint url.URL  `flag:";;;schemes=https|wss,require-host"`
+			Callback *url.URL `flag:";;;require-host,schemes=http|https"`
+		}{},
+		ParseArgs: []string{
+			"-endpoint", "wss://example.com/ws",
+			"-callback", "http://example.com/cb",
+		},
+		ExpF: &struct {
+			Endpoint url.URL  `flag:";;;schemes=https|wss,require-host"`
+			Callback *url.URL `flag:";;;require-host,schemes=http|https"`
+		}{
+			*mustParseURL("wss://example.com/ws"),
+			mustParseURL("http://example.com/cb"),
+		},
+	}, {
+		Name: "URL validation scheme",
+		F: &struct {
+			Endpoint url.URL `flag:";;;schemes=https|wss"`
+		}{},
+		ParseArgs: []string{
+			"-endpoint", "http://example.com",
+		},
+		ErrParse:      `invalid value "http://example.com" for flag -endpoint: URL scheme "http" is not one of: https, wss`,
+		ErrPFlagParse: `invalid argument "http://example.com" for "--endpoint" flag: URL scheme "http" is not one of: https, wss`,
+	}, {
+		Name: "URL validation host",
+		F: &struct {
+			Endpoint url.URL `flag:";;;require-host"`
+		}{},
+		ParseArgs: []string{
+			"-endpoint", "/relative/path",
+		},
+		ErrParse:      `invalid value "/relative/path" for flag -endpoint: URL must have a host`,
+		ErrPFlagParse: `invalid argument "/relative/path" for "--endpoint" flag: URL must have a host`,
+	}, {
+		Name: "mail.Address",
+		F: &struct {
+			To   mail.Address
+			From *mail.Address `flag:";Admin <admin@example.com>"`
+		}{},
+		ParseArgs: []string{
+			"-to", "Gopher <gopher@example.com>",
+		},
+		ExpF: &struct {
+			To   mail.Address
+			From *mail.Address `flag:";Admin <admin@example.com>"`
+		}{
+			mail.Address{Name: "Gopher", Address: "gopher@example.com"},
+			&mail.Address{Name: "Admin", Address: "admin@example.com"},
+		},
+	}, {
+		Name: "net.TCPAddr and net.UDPAddr",
+		F: &struct {
+			Listen  *net.TCPAddr
+			Metrics net.UDPAddr `flag:";127.0.0.1:8125"`
+		}{},
+		ParseArgs: []string{
+			"-listen", "127.0.0.1:8080",
+		},
+		ExpF: &struct {
+			Listen  *net.TCPAddr
+			Metrics net.UDPAddr `flag:";127.0.0.1:8125"`
+		}{
+			&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8125},
+		},
+	}, {
+		Name: "net.TCPAddr invalid",
+		F: &struct {
+			Listen net.TCPAddr
+		}{},
+		ParseArgs: []string{
+			"-listen", "127.0.0.1",
+		},
+		ErrParse:      `invalid value "127.0.0.1" for flag -listen: address 127.0.0.1: missing port in address`,
+		ErrPFlagParse: `invalid argument "127.0.0.1" for "--listen" flag: address 127.0.0.1: missing port in address`,
+	}, {
+		Name: "mail.Address invalid",
+		F: &struct {
+			To mail.Address
+		}{},
+		ParseArgs: []string{
+			"-to", "not an address",
+		},
+		ErrParse:      `invalid value "not an address" for flag -to: mail: no angle-addr`,
+		ErrPFlagParse: `invalid argument "not an address" for "--to" flag: mail: no angle-addr`,
 	}, {
 		Name: "NoAutoFlatten",
 		Opts: []Option{NoAutoFlatten()},
@@ -453,6 +1004,60 @@ var tests = []BindTest{
 		ExpF: &struct {
 			http.Client
 		}{http.Client{Timeout: 5 * time.Second}},
+	}, {
+		Name: "NameSplitter",
+		Opts: []Option{NameSplitter(SplitterFunc(func(name string) []string {
+			return []string{"x", strings.ToLower(name)}
+		}))},
+		F: &struct {
+			Nested struct{ Name string }
+		}{},
+		ParseArgs: []string{
+			"-x-nested-x-name=value",
+		},
+		ExpF: &struct {
+			Nested struct{ Name string }
+		}{struct{ Name string }{"value"}},
+	}, {
+		Name: "choices",
+		F: &struct {
+			Format string `flag:";json;;choices=json|yaml"`
+		}{},
+		ParseArgs: []string{
+			"-format", "yaml",
+		},
+		ExpF: &struct {
+			Format string `flag:";json;;choices=json|yaml"`
+		}{"yaml"},
+	}, {
+		Name: "choices invalid",
+		F: &struct {
+			Format string `flag:";json;;choices=json|yaml"`
+		}{},
+		ParseArgs: []string{
+			"-format", "xml",
+		},
+		ErrParse:      `invalid value "xml" for flag -format: "xml" is not one of: json, yaml`,
+		ErrPFlagParse: `invalid argument "xml" for "--format" flag: "xml" is not one of: json, yaml`,
+	}, {
+		Name: "choices invalid default",
+		F: &struct {
+			Format string `flag:";xml;;choices=json|yaml"`
+		}{},
+		ErrBind: `Format: cannot assign default value "xml": ` +
+			`"xml" is not one of: json, yaml`,
+	}, {
+		Name: "SeparatorOpt",
+		Opts: []Option{SeparatorOpt("_")},
+		F: &struct {
+			Nested struct{ FlagName string }
+		}{},
+		ParseArgs: []string{
+			"-nested_flag_name=value",
+		},
+		ExpF: &struct {
+			Nested struct{ FlagName string }
+		}{struct{ FlagName string }{"value"}},
 	}, {
 		Name: "Marshaler",
 		F: &struct {
@@ -477,6 +1082,646 @@ var tests = []BindTest{
 	},
 }
 
+// TestBindCount uses a fresh struct for each FlagSet since counts accumulate
+// across runs.
+func TestBindCount(t *testing.T) {
+	type Flags struct {
+		Verbose int `flag:"verbose;;;count"`
+		Level   int `flag:";2;;count"`
+	}
+	for _, test := range []struct {
+		BindTest
+		Init Flags
+	}{{
+		BindTest: BindTest{
+			Name: "count",
+			ParseArgs: []string{
+				"-verbose", "-verbose", "-verbose",
+				"-level",
+			},
+			ExpF: &Flags{3, 3},
+		},
+	}, {
+		BindTest: BindTest{
+			Name:      "count with value",
+			ParseArgs: []string{"-verbose=2", "-level"},
+			ExpF:      &Flags{2, 6},
+		},
+		Init: Flags{Level: 5},
+	}} {
+		test := test
+		f := test.Init
+		test.F = &f
+		t.Run(test.Name, test.test)
+
+		f = test.Init
+		test.UsePFlag = true
+		t.Run(test.Name+" pflag", test.test)
+	}
+}
+
+func TestStrictShortNamesSTDFlag(t *testing.T) {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	err := Bind(fs, &struct {
+		Long bool `flag:"long,l"`
+	}{}, StrictShortNames())
+	assert.EqualError(t, err, ErrorShortName{"Long", "l"}.Error())
+
+	pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	err = Bind(pfs, &struct {
+		Long bool `flag:"long,l"`
+	}{}, StrictShortNames())
+	assert.NoError(t, err)
+}
+
+func TestBindStringArray(t *testing.T) {
+	var f struct {
+		Slice []string
+		Array []string `flag:";;;array"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{
+		"--slice", "a,b", "--slice", "c",
+		"--array", "a,b", "--array", "c",
+	}))
+	assert.Equal(t, []string{"a", "b", "c"}, f.Slice)
+	assert.Equal(t, []string{"a,b", "c"}, f.Array)
+}
+
+type interfaceTestPlugin struct {
+	Addr string `flag:";:80;Plugin address"`
+}
+
+func TestBindInterface(t *testing.T) {
+	var port int
+	var f struct {
+		Plugin interface{}
+		Port   interface{}
+		Nil    interface{}
+		Value  interface{}
+	}
+	f.Plugin = &interfaceTestPlugin{}
+	f.Port = &port
+	f.Value = interfaceTestPlugin{}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{
+		"--plugin-addr", ":8080", "--port", "5"}))
+	assert.Equal(t, &interfaceTestPlugin{":8080"}, f.Plugin)
+	assert.Equal(t, 5, port)
+	assert.Nil(t, fs.Lookup("nil"))
+	assert.Nil(t, fs.Lookup("value-addr"))
+}
+
+func TestBindHiddenGroup(t *testing.T) {
+	var f struct {
+		Basic    int
+		Advanced struct {
+			Retries int
+			Nested  struct{ Depth int }
+		} `flag:";;;hidden"`
+		Servers map[string]struct{ Host string } `flag:";;;keys=a,hidden"`
+		Binder  overrideShortBinder              `flag:";;;hidden"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	user := fs.String("user", "", "Defined before Bind")
+	require.NoError(t, Bind(fs, &f))
+	assert.False(t, fs.Lookup("basic").Hidden)
+	assert.True(t, fs.Lookup("advanced-retries").Hidden)
+	assert.True(t, fs.Lookup("advanced-nested-depth").Hidden)
+	assert.True(t, fs.Lookup("servers-a-host").Hidden)
+	assert.True(t, fs.Lookup("binder-level").Hidden)
+	assert.False(t, fs.Lookup("user").Hidden,
+		"flags defined before Bind are not part of any group")
+
+	require.NoError(t, fs.Parse([]string{"--advanced-retries", "3",
+		"--user", "admin"}))
+	assert.Equal(t, 3, f.Advanced.Retries)
+	assert.Equal(t, "admin", *user)
+	assert.Equal(t, "", f.Servers["a"].Host)
+}
+
+func TestBindSepOption(t *testing.T) {
+	var f struct {
+		DB struct {
+			Host string
+		} `flag:"db;;;sep=."`
+		Servers map[string]struct{ Host string } `flag:";;;keys=a,sep=_"`
+		Flat    struct{ Port int }               `flag:";;;flatten,sep=."`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.NotNil(t, fs.Lookup("db.host"))
+	assert.NotNil(t, fs.Lookup("servers_a_host"))
+	assert.NotNil(t, fs.Lookup("port"))
+}
+
+func TestVerbatimNames(t *testing.T) {
+	var f struct {
+		StringFlag string
+		Server     struct{ HostName string }
+		Named      int `flag:"named-flag"`
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, VerbatimNames()))
+	assert.NotNil(t, fs.Lookup("StringFlag"))
+	assert.NotNil(t, fs.Lookup("Server-HostName"))
+	assert.NotNil(t, fs.Lookup("named-flag"))
+}
+
+func TestNameFunc(t *testing.T) {
+	var f struct {
+		ServerConfig struct{ HostName string }
+		Named        int `flag:"named-flag"`
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, NameFunc(func(name string) string {
+		return FromCamelCase(strings.TrimSuffix(name, "Config"), "_")
+	})))
+	assert.NotNil(t, fs.Lookup("server-host_name"))
+	assert.NotNil(t, fs.Lookup("named-flag"))
+}
+
+type overrideShortBinder struct{}
+
+func (overrideShortBinder) FlagBind(fs FlagSet, prefix string, opt Option) error {
+	var level int
+	fs.(PFlagSet).IntVarP(&level, prefix+"level", "", 0, "")
+	return nil
+}
+
+func TestOverrideShortName(t *testing.T) {
+	var f struct {
+		Client struct {
+			Timeout time.Duration
+		} `flag:";;;flatten"`
+		_ struct{} `flag:"timeout,t;5s;Request timeout"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, "t", fs.Lookup("timeout").Shorthand)
+	assert.Equal(t, "Request timeout", fs.Lookup("timeout").Usage)
+	require.NoError(t, fs.Parse([]string{"-t", "1s"}))
+	assert.Equal(t, time.Second, f.Client.Timeout)
+
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(std, &f))
+
+	var b struct {
+		Binder overrideShortBinder `flag:";;;flatten"`
+		_      struct{}            `flag:"level,l"`
+	}
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	assert.EqualError(t, Bind(fs, &b),
+		ErrorFlagOverrideShortName{"level", "l"}.Error())
+}
+
+func TestOverrideGlob(t *testing.T) {
+	var f struct {
+		Client struct {
+			Host string
+			Port int
+		}
+		Other int
+		_     struct{} `flag:"client-*;;Client setting;hidden"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	for _, name := range []string{"client-host", "client-port"} {
+		assert.True(t, fs.Lookup(name).Hidden, name)
+		assert.Equal(t, "Client setting", fs.Lookup(name).Usage, name)
+	}
+	assert.False(t, fs.Lookup("other").Hidden)
+
+	var g struct {
+		_ struct{} `flag:"missing-*;;;hidden"`
+	}
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	assert.EqualError(t, Bind(std, &g),
+		ErrorFlagOverrideUndefined{"missing-*"}.Error())
+}
+
+func TestOverrideHiddenDeprecated(t *testing.T) {
+	type Flags struct {
+		Client struct {
+			Timeout time.Duration
+			Retries int `flag:";;;hidden"`
+		} `flag:";;;flatten"`
+		Old int      `flag:";;;deprecated=use --new"`
+		_   struct{} `flag:"timeout;;;deprecated=use --request-timeout"`
+		_   struct{} `flag:"retries;;Retry count"`
+	}
+
+	var f Flags
+	var out bytes.Buffer
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.SetOutput(&out)
+	require.NoError(t, Bind(fs, &f))
+	assert.True(t, fs.Lookup("timeout").Hidden)
+	assert.Equal(t, "use --request-timeout", fs.Lookup("timeout").Deprecated)
+	assert.True(t, fs.Lookup("retries").Hidden)
+	assert.True(t, fs.Lookup("old").Hidden)
+	require.NoError(t, fs.Parse([]string{"--timeout", "1s"}))
+	assert.Equal(t, "Flag --timeout has been deprecated, use --request-timeout\n",
+		out.String())
+
+	f = Flags{}
+	out.Reset()
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	std.SetOutput(&out)
+	require.NoError(t, Bind(std, &f))
+	assert.Equal(t, "(deprecated: use --new)", std.Lookup("old").Usage)
+	require.NoError(t, std.Parse([]string{"-old", "1"}))
+	assert.Equal(t, 1, f.Old)
+	assert.Equal(t, "Flag -old has been deprecated, use --new\n",
+		out.String())
+}
+
+type fieldBinderTest struct {
+	Value string
+	Field reflect.StructField
+}
+
+func (f *fieldBinderTest) FlagBindField(fs FlagSet, field reflect.StructField,
+	prefix string, opt Option) error {
+	f.Field = field
+	tag, err := newFlagTag(field.Tag.Get("flag"))
+	if err != nil {
+		return err
+	}
+	fs.StringVar(&f.Value, prefix+tag.Name, tag.DefValue, tag.Usage)
+	return nil
+}
+
+func TestFieldBinder(t *testing.T) {
+	var f struct {
+		Nested struct {
+			Custom fieldBinderTest `flag:"custom;def;Custom usage"`
+		}
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, "Custom", f.Nested.Custom.Field.Name)
+	flg := fs.Lookup("nested-custom")
+	require.NotNil(t, flg)
+	assert.Equal(t, "Custom usage", flg.Usage)
+	assert.Equal(t, "def", f.Nested.Custom.Value)
+
+	infos, err := Inspect(&f)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "Nested.Custom", infos[0].Path)
+}
+
+type contextKey struct{}
+
+type contextBinder struct{ Value interface{} }
+
+func (c *contextBinder) FlagBind(fs FlagSet, prefix string, opt Option) error {
+	c.Value = OptionContext(opt).Value(contextKey{})
+	return nil
+}
+
+// nestedContextBinder binds its fields with Bind and its Option.
+type nestedContextBinder struct {
+	Binder contextBinder
+}
+
+func (n *nestedContextBinder) FlagBind(fs FlagSet, prefix string,
+	opt Option) error {
+	return Bind(fs, &n.Binder, opt)
+}
+
+func TestBindContext(t *testing.T) {
+	var f struct {
+		Binder contextBinder
+		Nested nestedContextBinder
+		Int    int
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, BindContext(ctx, fs, &f))
+	assert.Equal(t, "value", f.Binder.Value)
+	assert.Equal(t, "value", f.Nested.Binder.Value)
+	assert.NotNil(t, fs.Lookup("int"))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	fs = flag.NewFlagSet("", flag.ContinueOnError)
+	assert.Equal(t, context.Canceled, BindContext(ctx, fs, &f))
+
+	require.NoError(t, Bind(flag.NewFlagSet("", flag.ContinueOnError), &f))
+	assert.Nil(t, f.Binder.Value)
+}
+
+func TestPFlagNormalizeFunc(t *testing.T) {
+	var f struct {
+		MaxConns int `flag:"max_conns"`
+		Other    int
+	}
+	normalize := PFlagNormalizeFunc(
+		func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+			return pflag.NormalizedName(strings.ReplaceAll(name, "_", "-"))
+		})
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, normalize))
+	require.NoError(t, fs.Parse([]string{"--max_conns", "1", "--other", "2"}))
+	assert.Equal(t, 1, f.MaxConns)
+	assert.Equal(t, 2, f.Other)
+	require.NoError(t, fs.Parse([]string{"--max-conns", "3"}))
+	assert.Equal(t, 3, f.MaxConns)
+
+	infos, err := Inspect(&f, normalize)
+	require.NoError(t, err)
+	assert.Equal(t, "max-conns", infos[0].Name)
+
+	// The standard flag package is unaffected.
+	require.NoError(t, Bind(flag.NewFlagSet("", flag.ContinueOnError),
+		&f, normalize))
+}
+
+func TestBindAliases(t *testing.T) {
+	type Flags struct {
+		Nested struct {
+			Timeout time.Duration `flag:";5s;;aliases=wait|legacy-timeout"`
+			Verbose bool          `flag:";;;aliases=debug"`
+		}
+	}
+
+	var f Flags
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	alias := fs.Lookup("nested-legacy-timeout")
+	require.NotNil(t, alias)
+	assert.True(t, alias.Hidden)
+	assert.Equal(t, "5s", alias.DefValue)
+	require.NoError(t, fs.Parse([]string{
+		"--nested-wait", "1s", "--nested-debug"}))
+	assert.Equal(t, time.Second, f.Nested.Timeout)
+	assert.True(t, f.Nested.Verbose)
+
+	f = Flags{}
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(std, &f))
+	assert.Equal(t, "Alias for nested-timeout",
+		std.Lookup("nested-wait").Usage)
+	require.NoError(t, std.Parse([]string{
+		"-nested-legacy-timeout", "2s", "-nested-debug"}))
+	assert.Equal(t, 2*time.Second, f.Nested.Timeout)
+	assert.True(t, f.Nested.Verbose)
+}
+
+type defaultsSetterNested struct {
+	Retries int `flag:";3"`
+	Timeout int `flag:";10"`
+}
+
+func (d *defaultsSetterNested) SetDefaults() { d.Timeout = 30 }
+
+type defaultsSetterFlags struct {
+	Host   string `flag:";localhost"`
+	Nested *defaultsSetterNested
+}
+
+func (d *defaultsSetterFlags) SetDefaults() { d.Host = "example.com" }
+
+func TestDefaultsSetter(t *testing.T) {
+	var f defaultsSetterFlags
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, "example.com", f.Host)
+	assert.Equal(t, 3, f.Nested.Retries)
+	assert.Equal(t, 30, f.Nested.Timeout)
+	assert.Equal(t, "30", fs.Lookup("nested-timeout").DefValue)
+}
+
+type unexportedConfig struct {
+	Host string `flag:";localhost"`
+	port int
+}
+
+type unexportedNested struct {
+	Timeout time.Duration
+}
+
+func TestBindEmbeddedUnexported(t *testing.T) {
+	var f struct {
+		unexportedConfig
+		*unexportedNested `flag:"nested"`
+	}
+	f.unexportedNested = &unexportedNested{}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, "localhost", f.Host)
+	require.NoError(t, fs.Parse([]string{
+		"--host", "example.com", "--nested-timeout", "1s"}))
+	assert.Equal(t, "example.com", f.Host)
+	assert.Equal(t, time.Second, f.Timeout)
+	assert.Nil(t, fs.Lookup("port"))
+
+	var g struct{ *unexportedNested }
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &g))
+	assert.Nil(t, fs.Lookup("timeout"), "nil pointer is skipped")
+}
+
+func TestBindFuncs(t *testing.T) {
+	type Flags struct {
+		AddHeader func(string) error
+		Reset     func() error
+		Unset     func(string) error
+	}
+	var headers []string
+	var resets int
+	newFlags := func() *Flags {
+		return &Flags{
+			AddHeader: func(header string) error {
+				if !strings.Contains(header, "=") {
+					return errors.New("missing =")
+				}
+				headers = append(headers, header)
+				return nil
+			},
+			Reset: func() error {
+				resets++
+				return nil
+			},
+		}
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		headers, resets = nil, 0
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		require.NoError(t, Bind(fs, newFlags()))
+		assert.Nil(t, fs.Lookup("unset"), "nil func is skipped")
+		require.NoError(t, fs.Parse([]string{"-add-header", "a=1",
+			"-reset", "-add-header", "b=2", "-reset=false"}))
+		assert.Equal(t, []string{"a=1", "b=2"}, headers)
+		assert.Equal(t, 1, resets)
+		assert.Error(t, fs.Set("add-header", "c"))
+	})
+	t.Run("pflag", func(t *testing.T) {
+		headers, resets = nil, 0
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		require.NoError(t, Bind(fs, newFlags()))
+		require.NoError(t, fs.Parse([]string{"--add-header", "a=1",
+			"--reset", "--reset"}))
+		assert.Equal(t, []string{"a=1"}, headers)
+		assert.Equal(t, 2, resets)
+		assert.Contains(t, fs.FlagUsages(), "--add-header string")
+		assert.NotContains(t, fs.FlagUsages(), "default")
+	})
+}
+
+func TestRequireTags(t *testing.T) {
+	var f struct {
+		Tagged string `flag:";;Tagged usage"`
+		Nested struct {
+			Tagged   int `flag:"tagged"`
+			Untagged int
+		}
+		Ignored int `flag:"-"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	err := Bind(fs, &f, RequireTags())
+	assert.Equal(t, ErrorNestedStruct{"Nested",
+		ErrorMissingTag{"Untagged"}}, err)
+	assert.EqualError(t, err, "Nested: Untagged: missing flag tag")
+
+	var g struct {
+		Tagged string `flag:";;Tagged usage"`
+		Nested struct {
+			Tagged int `flag:"tagged"`
+		}
+	}
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	assert.NoError(t, Bind(fs, &g, RequireTags()))
+}
+
+func TestIgnoreUntagged(t *testing.T) {
+	var f struct {
+		Tagged   string `flag:";;Tagged usage"`
+		Untagged string
+		Nested   struct {
+			Tagged   int `flag:"tagged"`
+			Untagged int
+		}
+	}
+	infos, err := Inspect(&f, IgnoreUntagged())
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"tagged", "nested-tagged"}, names)
+}
+
+func TestNameTags(t *testing.T) {
+	var f struct {
+		Listen  string `mapstructure:"listen_addr"`
+		Timeout int    `mapstructure:",omitempty" envconfig:"REQUEST_TIMEOUT"`
+		Level   string `envconfig:"logLevel"`
+		Name    string `mapstructure:"svc" flag:"name"`
+		Other   int    `mapstructure:"-"`
+		DB      struct {
+			Host string `mapstructure:"host"`
+		} `mapstructure:"database"`
+	}
+	infos, err := Inspect(&f, NameTags("mapstructure", "envconfig"))
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"listen-addr", "request-timeout", "log-level",
+		"name", "other", "database-host"}, names)
+}
+
+func TestUseJSONNames(t *testing.T) {
+	var f struct {
+		Listen  string `json:"listen_addr,omitempty"`
+		Timeout int    `json:",omitempty" mapstructure:"request_timeout"`
+		Skip    int    `json:"-"`
+		Name    string `json:"svc" flag:"name"`
+		DB      struct {
+			Host string `json:"host"`
+		} `json:"db"`
+	}
+	infos, err := Inspect(&f, UseJSONNames(), NameTags("mapstructure"))
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"listen_addr", "request-timeout", "skip",
+		"name", "db-host"}, names)
+}
+
+func TestOnError(t *testing.T) {
+	var f struct {
+		Value int `flag:";x"`
+	}
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	assert.Error(t, Bind(fs, &f, OnError(ContinueOnError)))
+
+	fs = flag.NewFlagSet("", flag.ContinueOnError)
+	assert.Panics(t, func() { Bind(fs, &f, OnError(PanicOnError)) })
+
+	defer func(fn func(int)) { exit = fn }(exit)
+	var code int
+	exit = func(c int) { code = c }
+	var out bytes.Buffer
+	fs = flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	err := Bind(fs, &f, OnError(ExitOnError))
+	assert.Equal(t, 2, code)
+	assert.Equal(t, err.Error()+"\n", out.String())
+}
+
+func TestBindDefaultNotSet(t *testing.T) {
+	type Flags struct {
+		Int   int      `flag:";5"`
+		Slice []string `flag:";a,b"`
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		var f Flags
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		require.NoError(t, Bind(fs, &f))
+		assert.Equal(t, 5, f.Int)
+		fs.Visit(func(f *flag.Flag) {
+			t.Errorf("flag %q is set by its default", f.Name)
+		})
+	})
+	t.Run("pflag", func(t *testing.T) {
+		var f Flags
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		require.NoError(t, Bind(fs, &f))
+		assert.Equal(t, []string{"a", "b"}, f.Slice)
+		assert.False(t, fs.Changed("int"))
+		assert.False(t, fs.Changed("slice"))
+		assert.Equal(t, "5", fs.Lookup("int").DefValue)
+
+		// The default is replaced, not appended to.
+		require.NoError(t, fs.Parse([]string{"--slice", "c"}))
+		assert.Equal(t, []string{"c"}, f.Slice)
+		assert.True(t, fs.Changed("slice"))
+	})
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
 func mustParseURL(rawurl string) *url.URL {
 	u, err := url.Parse(rawurl)
 	if err != nil {
@@ -484,3 +1729,213 @@ func mustParseURL(rawurl string) *url.URL {
 	}
 	return u
 }
+
+// largeStructType returns a struct type with n fields of various supported
+// types, similar to a large generated config struct.
+func largeStructType(n int) reflect.Type {
+	types := []reflect.Type{
+		reflect.TypeOf(""),
+		reflect.TypeOf(0),
+		reflect.TypeOf(false),
+		reflect.TypeOf(time.Duration(0)),
+		reflect.TypeOf(StructA{}),
+	}
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("Field%d", i),
+			Type: types[i%len(types)],
+			Tag:  `flag:";;Usage for this field"`,
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+// TestBindAllocs guards against regressions in the number of allocations per
+// bound field, including those made by the FlagSet itself.
+func TestBindAllocs(t *testing.T) {
+	const n = 100
+	typ := largeStructType(n)
+	allocs := testing.AllocsPerRun(100, func() {
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		if err := Bind(fs, reflect.New(typ).Interface()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if perField := allocs / n; perField > 4 {
+		t.Errorf("%v allocs per field, want at most 4", perField)
+	}
+}
+
+func benchmarkBind(b *testing.B, n int, newFlagSet func() FlagSet) {
+	typ := largeStructType(n)
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			err := Bind(newFlagSet(), reflect.New(typ).Interface())
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fieldCache.Delete(typ)
+			fieldCache.Delete(reflect.TypeOf(StructA{}))
+			err := Bind(newFlagSet(), reflect.New(typ).Interface())
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBind(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		b.Run(fmt.Sprintf("flag/%d", n), func(b *testing.B) {
+			benchmarkBind(b, n, func() FlagSet {
+				return flag.NewFlagSet("", flag.ContinueOnError)
+			})
+		})
+		b.Run(fmt.Sprintf("pflag/%d", n), func(b *testing.B) {
+			benchmarkBind(b, n, func() FlagSet {
+				return pflag.NewFlagSet("", pflag.ContinueOnError)
+			})
+		})
+	}
+}
+
+func TestPrefixFilter(t *testing.T) {
+	type Flags struct {
+		DB struct {
+			Host string
+			Port int
+		}
+		Debug struct {
+			Pprof bool
+		}
+		_       struct{} `flag:"db-port;5432"`
+		Verbose bool
+		_       struct{} `flag:"verbose;;Verbose output"`
+	}
+
+	var f Flags
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, IncludePrefix("db-")))
+	assert.Equal(t, []string{"db-host", "db-port"}, flagNames(fs))
+	assert.Equal(t, 5432, f.DB.Port)
+
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, IncludePrefix("db-"),
+		IncludePrefix("debug-", "verbose"), ExcludePrefix("db-port")))
+	assert.Equal(t, []string{"db-host", "debug-pprof", "verbose"},
+		flagNames(fs))
+
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Prefix("app-"), ExcludePrefix("app-d")))
+	assert.Equal(t, []string{"app-verbose"}, flagNames(fs))
+}
+
+func TestOnlyExcept(t *testing.T) {
+	var f struct {
+		Timeout    int
+		Retries    int
+		LegacyMode bool
+		HTTP       struct{ Timeout int }
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Only("timeout", "retries"),
+		Only("http-timeout")))
+	assert.Equal(t, []string{"http-timeout", "retries", "timeout"},
+		flagNames(fs))
+
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Except("legacy-mode")))
+	assert.Equal(t, []string{"http-timeout", "retries", "timeout"},
+		flagNames(fs))
+
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, IncludePrefix("http-"), Only("retries"),
+		Except("http-timeout")))
+	assert.Equal(t, []string{"retries"}, flagNames(fs))
+}
+
+func TestSkipExisting(t *testing.T) {
+	var a struct {
+		Host string `flag:";localhost"`
+		Tags []string
+		Port int
+	}
+	var b struct {
+		Host  string
+		Tags  []string
+		Debug bool
+	}
+	a.Tags = []string{"x"}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &a))
+	assert.Error(t, Bind(fs, &b))
+
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &a))
+	require.NoError(t, Bind(fs, &b, SkipExisting()))
+	assert.Equal(t, "localhost", b.Host)
+	assert.Equal(t, []string{"x"}, b.Tags)
+	assert.NotNil(t, fs.Lookup("debug"))
+
+	require.NoError(t, fs.Parse([]string{"--host", "example.com",
+		"--tags", "y", "--tags", "z", "--port", "8080"}))
+	assert.Equal(t, "example.com", a.Host)
+	assert.Equal(t, "example.com", b.Host)
+	assert.Equal(t, []string{"y", "z"}, a.Tags)
+	assert.Equal(t, []string{"y", "z"}, b.Tags)
+	assert.Equal(t, 8080, a.Port)
+
+	var mismatch struct {
+		Server struct {
+			Port string `flag:"port;80"`
+		} `flag:";;;flatten"`
+	}
+	err := Bind(fs, &mismatch, SkipExisting())
+	assert.EqualError(t, err, `Server.Port: cannot share existing flag `+
+		`"port": type int does not match string`)
+	var existing ErrorExistingFlag
+	require.True(t, errors.As(err, &existing))
+	assert.Equal(t, "port", existing.FlagName)
+
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	std.String("host", "std.example.com", "")
+	var c struct{ Host string }
+	require.NoError(t, Bind(std, &c, SkipExisting()))
+	assert.Equal(t, "std.example.com", c.Host)
+}
+
+func TestOverrideExisting(t *testing.T) {
+	var f struct {
+		Timeout time.Duration `flag:";5s;Request timeout"`
+		Verbose bool
+	}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	libTimeout := fs.IntP("timeout", "t", 30, "Timeout in seconds")
+	fs.Bool("verbose", false, "Verbose output")
+	require.NoError(t, Bind(fs, &f, OverrideExisting()))
+	timeout := fs.Lookup("timeout")
+	assert.Equal(t, "Request timeout", timeout.Usage)
+	assert.Equal(t, "5s", timeout.DefValue)
+	assert.Equal(t, "duration", timeout.Value.Type())
+	assert.Equal(t, "Verbose output", fs.Lookup("verbose").Usage)
+
+	require.NoError(t, fs.Parse([]string{"-t", "1m", "--verbose"}))
+	assert.Equal(t, time.Minute, f.Timeout)
+	assert.True(t, f.Verbose)
+	assert.Equal(t, 30, *libTimeout)
+
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	std.Int("timeout", 30, "Timeout in seconds")
+	require.NoError(t, Bind(std, &f, OverrideExisting()))
+	require.NoError(t, std.Parse([]string{"-timeout", "2s"}))
+	assert.Equal(t, 2*time.Second, f.Timeout)
+}
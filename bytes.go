@@ -0,0 +1,52 @@
+package flagbind
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// BytesHex is a []byte flag.Value that is hex encoded.
+type BytesHex []byte
+
+func (data *BytesHex) Set(text string) error {
+	b, err := hex.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return err
+	}
+	*data = b
+	return nil
+}
+
+func (data BytesHex) String() string {
+	return hex.EncodeToString(data)
+}
+
+func (data BytesHex) Type() string { return "bytesHex" }
+
+// BytesBase64 is a []byte flag.Value that is standard base64 encoded.
+type BytesBase64 []byte
+
+func (data *BytesBase64) Set(text string) error {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return err
+	}
+	*data = b
+	return nil
+}
+
+func (data BytesBase64) String() string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func (data BytesBase64) Type() string { return "bytesBase64" }
+
+// validEncoding reports whether encoding is a supported []byte encoding.
+func validEncoding(encoding string) bool {
+	switch encoding {
+	case "", "hex", "base64":
+		return true
+	}
+	return false
+}
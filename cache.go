@@ -0,0 +1,83 @@
+package flagbind
+
+import (
+	"reflect"
+	"sync"
+)
+
+// cachedField is the parsed layout of a struct field that may define or
+// override a flag.
+type cachedField struct {
+	reflect.StructField
+	Tag        flagTag
+	HasTag     bool
+	IsMetadata bool
+
+	// TagErr is the error from parsing the flag tag, if it is malformed.
+	TagErr error
+
+	// Words is the field name split by the CamelCaseSplitter.
+	Words []string
+}
+
+// fieldCache holds the []cachedField for each struct reflect.Type that has
+// been bound, so that repeated calls to Bind for the same type do not re-parse
+// its tags.
+var fieldCache sync.Map
+
+// cachedFields returns the fields of the struct type t that are not ignored,
+// with their flag tags parsed and any Extended Usage loaded.
+func cachedFields(t reflect.Type) []cachedField {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]cachedField)
+	}
+
+	var fields []cachedField
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+
+		// Special flag metadata may be set using the blank identifier.
+		isMetadata := structField.Name == "_"
+
+		// See reflect.StructField for details.
+		isExported := structField.PkgPath == ""
+
+		// Ignore unexported, non-metadata fields, except for embedded
+		// structs, whose exported fields are promoted.
+		if !isExported && !isMetadata && !isEmbeddedStruct(structField) {
+			continue
+		}
+
+		// Parse the flagTag.
+		tagStr, hasTag := structField.Tag.Lookup("flag")
+		tag, tagErr := newFlagTag(tagStr)
+
+		if tag.IsIgnored && tagErr == nil {
+			continue
+		}
+
+		i = loadExtendedUsage(i, t, &tag)
+
+		fields = append(fields, cachedField{
+			StructField: structField,
+			Tag:         tag,
+			HasTag:      hasTag,
+			IsMetadata:  isMetadata,
+			TagErr:      tagErr,
+			Words:       CamelCaseSplitter{}.Split(structField.Name),
+		})
+	}
+
+	cached, _ := fieldCache.LoadOrStore(t, fields)
+	return cached.([]cachedField)
+}
+
+// isEmbeddedStruct reports whether field is an embedded struct or struct
+// pointer.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return field.Anonymous && t.Kind() == reflect.Struct
+}
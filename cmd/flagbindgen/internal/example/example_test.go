@@ -0,0 +1,62 @@
+package example
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/AdamSLevy/flagbind"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindFlagsSTDFlag(t *testing.T) {
+	var bound, generated Flags
+	boundFS := flag.NewFlagSet("", flag.ContinueOnError)
+	generatedFS := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, flagbind.Bind(boundFS, &bound))
+	require.NoError(t, BindFlags(generatedFS, &generated))
+	assert.Equal(t, bound, generated)
+
+	var boundFlags, generatedFlags []flag.Flag
+	boundFS.VisitAll(func(f *flag.Flag) {
+		boundFlags = append(boundFlags, flag.Flag{
+			Name: f.Name, Usage: f.Usage, Value: f.Value})
+	})
+	generatedFS.VisitAll(func(f *flag.Flag) {
+		generatedFlags = append(generatedFlags, flag.Flag{
+			Name: f.Name, Usage: f.Usage, Value: f.Value})
+	})
+	assert.Equal(t, boundFlags, generatedFlags)
+}
+
+func TestBindFlagsPFlag(t *testing.T) {
+	var bound, generated Flags
+	boundFS := pflag.NewFlagSet("", pflag.ContinueOnError)
+	generatedFS := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, flagbind.Bind(boundFS, &bound))
+	require.NoError(t, BindFlags(generatedFS, &generated))
+	assert.Equal(t, bound, generated)
+
+	flags := func(fs *pflag.FlagSet) []pflag.Flag {
+		var flags []pflag.Flag
+		fs.VisitAll(func(f *pflag.Flag) {
+			flags = append(flags, pflag.Flag{
+				Name:      f.Name,
+				Shorthand: f.Shorthand,
+				Usage:     f.Usage,
+				Value:     f.Value,
+				Hidden:    f.Hidden,
+			})
+		})
+		return flags
+	}
+	assert.Equal(t, flags(boundFS), flags(generatedFS))
+	assert.Equal(t, "", generatedFS.Lookup("workers").DefValue)
+
+	args := []string{"-v", "--port", "80", "--server-host", "example.com",
+		"--db.host", "db.example.com"}
+	require.NoError(t, boundFS.Parse(args))
+	require.NoError(t, generatedFS.Parse(args))
+	assert.Equal(t, bound, generated)
+}
@@ -0,0 +1,37 @@
+// Package example is bound both by flagbind.Bind and by the BindFlags
+// function generated by flagbindgen to verify that they are equivalent.
+package example
+
+import "time"
+
+//go:generate go run github.com/AdamSLevy/flagbind/cmd/flagbindgen --type Flags
+
+type Flags struct {
+	Verbose bool     `flag:"v;;Verbose output"`
+	Port    int      `flag:"port,p;8080;Port to listen on"`
+	Offset  int64    `flag:";-1"`
+	Workers uint     `flag:";;;hide-default"`
+	Limit   uint64   `flag:";0x10"`
+	Ratio   float64  `flag:";0.5;;hidden"`
+	Name    string   `flag:";gopher;Name to greet"`
+	_       struct{} `use:"with extended usage"`
+
+	Timeout time.Duration `flag:";5s"`
+	Ignored string        `flag:"-"`
+	skipped string
+
+	Server Server
+	Client Server `flag:"cli"`
+	DB     Server `flag:"db."`
+	Flat   Server `flag:";;;flatten"`
+	Hidden Server `flag:";;;hidden"`
+	Embedded
+}
+
+type Server struct {
+	Host string `flag:";localhost"`
+}
+
+type Embedded struct {
+	Embedded bool
+}
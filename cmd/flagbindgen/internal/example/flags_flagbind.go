@@ -0,0 +1,85 @@
+// Code generated by flagbindgen; DO NOT EDIT.
+
+package example
+
+import (
+	"time"
+
+	"github.com/AdamSLevy/flagbind"
+)
+
+// BindFlags defines the same flags as flagbind.Bind(fs, v), without reflection.
+func BindFlags(fs flagbind.FlagSet, v *Flags) error {
+	if v.Port == 0 {
+		v.Port = 8080
+	}
+	if v.Offset == 0 {
+		v.Offset = -1
+	}
+	if v.Limit == 0 {
+		v.Limit = 0x10
+	}
+	if v.Ratio == 0 {
+		v.Ratio = 0.5
+	}
+	if v.Name == "" {
+		v.Name = "gopher"
+	}
+	if v.Timeout == 0 {
+		v.Timeout = time.Duration(5000000000)
+	}
+	if v.Server.Host == "" {
+		v.Server.Host = "localhost"
+	}
+	if v.Client.Host == "" {
+		v.Client.Host = "localhost"
+	}
+	if v.DB.Host == "" {
+		v.DB.Host = "localhost"
+	}
+	if v.Flat.Host == "" {
+		v.Flat.Host = "localhost"
+	}
+	if v.Hidden.Host == "" {
+		v.Hidden.Host = "localhost"
+	}
+	switch fs := fs.(type) {
+	case flagbind.STDFlagSet:
+		fs.BoolVar(&v.Verbose, "v", v.Verbose, "Verbose output")
+		fs.IntVar(&v.Port, "port", v.Port, "Port to listen on")
+		fs.Int64Var(&v.Offset, "offset", v.Offset, "")
+		fs.UintVar(&v.Workers, "workers", v.Workers, "")
+		fs.Lookup("workers").DefValue = ""
+		fs.Uint64Var(&v.Limit, "limit", v.Limit, "")
+		fs.Float64Var(&v.Ratio, "ratio", v.Ratio, "")
+		fs.StringVar(&v.Name, "name", v.Name, "Name to greet with extended usage")
+		fs.DurationVar(&v.Timeout, "timeout", v.Timeout, "")
+		fs.StringVar(&v.Server.Host, "server-host", v.Server.Host, "")
+		fs.StringVar(&v.Client.Host, "cli-host", v.Client.Host, "")
+		fs.StringVar(&v.DB.Host, "db.host", v.DB.Host, "")
+		fs.StringVar(&v.Flat.Host, "host", v.Flat.Host, "")
+		fs.StringVar(&v.Hidden.Host, "hidden-host", v.Hidden.Host, "")
+		fs.BoolVar(&v.Embedded.Embedded, "embedded", v.Embedded.Embedded, "")
+	case flagbind.PFlagSet:
+		fs.BoolVarP(&v.Verbose, "verbose", "v", v.Verbose, "Verbose output")
+		fs.IntVarP(&v.Port, "port", "p", v.Port, "Port to listen on")
+		fs.Int64VarP(&v.Offset, "offset", "", v.Offset, "")
+		fs.UintVarP(&v.Workers, "workers", "", v.Workers, "")
+		fs.Lookup("workers").DefValue = ""
+		fs.Uint64VarP(&v.Limit, "limit", "", v.Limit, "")
+		fs.Float64VarP(&v.Ratio, "ratio", "", v.Ratio, "")
+		fs.Lookup("ratio").Hidden = true
+		fs.StringVarP(&v.Name, "name", "", v.Name, "Name to greet with extended usage")
+		fs.DurationVarP(&v.Timeout, "timeout", "", v.Timeout, "")
+		fs.StringVarP(&v.Server.Host, "server-host", "", v.Server.Host, "")
+		fs.StringVarP(&v.Client.Host, "cli-host", "", v.Client.Host, "")
+		fs.StringVarP(&v.DB.Host, "db.host", "", v.DB.Host, "")
+		fs.StringVarP(&v.Flat.Host, "host", "", v.Flat.Host, "")
+		fs.StringVarP(&v.Hidden.Host, "hidden-host", "", v.Hidden.Host, "")
+		fs.Lookup("hidden-host").Hidden = true
+		fs.BoolVarP(&v.Embedded.Embedded, "embedded", "", v.Embedded.Embedded, "")
+	default:
+		return flagbind.ErrorInvalidFlagSet
+	}
+	return nil
+}
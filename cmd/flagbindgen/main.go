@@ -0,0 +1,438 @@
+// Command flagbindgen generates a function that binds the fields of a struct
+// type to a flagbind.FlagSet without using reflection.
+//
+// Usage:
+//
+//	//go:generate flagbindgen -type Flags
+//
+// This generates BindFlags(fs flagbind.FlagSet, v *Flags) error in the file
+// flags_flagbind.go, which defines the same flags as flagbind.Bind(fs, v).
+//
+// Only a subset of what flagbind.Bind supports may be generated: the bool,
+// int, int64, uint, uint64, float64, string, and time.Duration types, nested
+// and embedded structs declared in the same package, Extended Usage, and the
+// hidden, hide-default, and flatten options. Anything else, including an
+// invalid tag <default>, is reported as an error when generating.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdamSLevy/flagbind"
+	"github.com/spf13/pflag"
+)
+
+type Flags struct {
+	Type   string `flag:";;Name of the struct type to generate a bind function for"`
+	Output string `flag:";;Output file name (default <type>_flagbind.go)"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("flagbindgen: ")
+
+	var flags Flags
+	fs := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	if err := flagbind.Bind(fs, &flags); err != nil {
+		log.Fatal(err)
+	}
+	fs.Parse(os.Args[1:])
+
+	if flags.Type == "" {
+		log.Fatal("--type is required")
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if flags.Output == "" {
+		flags.Output = strings.ToLower(flags.Type) + "_flagbind.go"
+	}
+	output := filepath.Join(dir, flags.Output)
+
+	pkg, err := parsePackage(dir, output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := generate(pkg, flags.Type)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(output, src, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parsePackage parses the non-test Go files in dir, excluding any previously
+// generated output.
+func parsePackage(dir, output string) (*ast.Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") &&
+			info.Name() != filepath.Base(output)
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("no Go files in %v", dir)
+}
+
+// generate returns the formatted source of a file declaring the bind function
+// for typeName.
+func generate(pkg *ast.Package, typeName string) ([]byte, error) {
+	g := generator{structs: make(map[string]*ast.StructType)}
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				g.structs[spec.Name.Name] = st
+			}
+			return false
+		})
+	}
+
+	st, ok := g.structs[typeName]
+	if !ok {
+		return nil, fmt.Errorf("struct type %v not found", typeName)
+	}
+	if err := g.bindStruct(st, "v.", ""); err != nil {
+		return nil, fmt.Errorf("%v: %v", typeName, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by flagbindgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %v\n\n", pkg.Name)
+	fmt.Fprintf(&buf, "import (\n")
+	if g.usesTime {
+		fmt.Fprintf(&buf, "\t\"time\"\n\n")
+	}
+	fmt.Fprintf(&buf, "\t\"github.com/AdamSLevy/flagbind\"\n)\n\n")
+	fmt.Fprintf(&buf, "// Bind%v defines the same flags as flagbind.Bind(fs, v), "+
+		"without reflection.\n", typeName)
+	fmt.Fprintf(&buf, "func Bind%v(fs flagbind.FlagSet, v *%v) error {\n",
+		typeName, typeName)
+	buf.Write(g.defaults.Bytes())
+	fmt.Fprintf(&buf, "switch fs := fs.(type) {\n")
+	fmt.Fprintf(&buf, "case flagbind.STDFlagSet:\n")
+	buf.Write(g.std.Bytes())
+	fmt.Fprintf(&buf, "case flagbind.PFlagSet:\n")
+	buf.Write(g.pflag.Bytes())
+	fmt.Fprintf(&buf, "default:\nreturn flagbind.ErrorInvalidFlagSet\n}\n")
+	fmt.Fprintf(&buf, "return nil\n}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+type generator struct {
+	structs map[string]*ast.StructType
+
+	// defaults applies the tag <default> to zero fields.
+	defaults bytes.Buffer
+	// std and pflag define the flags for each kind of FlagSet.
+	std, pflag bytes.Buffer
+
+	usesTime bool
+
+	// hidden is true within a nested struct with the hidden option.
+	hidden bool
+}
+
+// flagTag is the subset of the flagbind tag that may be generated.
+type flagTag struct {
+	Name, ShortName string
+	HasExplicitName bool
+	DefValue, Usage string
+	HideDefault     bool
+	Hidden          bool
+	Flatten         bool
+}
+
+// parseTag parses the tag as flagbind.Bind does, and returns an error for any
+// option that may not be generated.
+func parseTag(tag string) (flagTag, error) {
+	var fTag flagTag
+	args := strings.Split(tag, ";")
+	if len(args) > 4 {
+		return fTag, fmt.Errorf("too many settings: %v", len(args))
+	}
+
+	names := strings.Split(args[0], ",")
+	if len(names) > 2 {
+		return fTag, fmt.Errorf("too many names: %q", args[0])
+	}
+	fTag.Name = strings.TrimLeft(names[0], "-")
+	if len(names) > 1 {
+		fTag.ShortName = strings.TrimLeft(names[1], "-")
+	}
+	if len(fTag.Name) < len(fTag.ShortName) {
+		fTag.Name, fTag.ShortName = fTag.ShortName, fTag.Name
+	}
+	if len(fTag.ShortName) > 1 {
+		fTag.ShortName = ""
+	}
+	if len(fTag.Name) == 1 {
+		fTag.ShortName = fTag.Name
+	}
+	fTag.HasExplicitName = fTag.Name != ""
+
+	if len(args) > 1 {
+		fTag.DefValue = args[1]
+	}
+	if len(args) > 2 {
+		fTag.Usage = strings.TrimSpace(args[2])
+	}
+	if len(args) > 3 && strings.TrimSpace(args[3]) != "" {
+		// Options are separated by commas, and list values by "|", so
+		// each option is a single element of the split.
+		for _, opt := range strings.Split(args[3], ",") {
+			switch strings.ToLower(strings.TrimSpace(opt)) {
+			case "":
+				return fTag, fmt.Errorf("empty option: %q", args[3])
+			case "hidden":
+				fTag.Hidden = true
+			case "hide-default":
+				fTag.HideDefault = true
+			case "flatten":
+				fTag.Flatten = true
+			default:
+				return fTag, fmt.Errorf(
+					"option %q is not supported by flagbindgen", opt)
+			}
+		}
+	}
+	return fTag, nil
+}
+
+// bindStruct generates the flags for the fields of st. The path is the
+// selector expression for the struct, such as "v.Nested.", and the prefix is
+// prepended to each flag name.
+func (g *generator) bindStruct(st *ast.StructType, path, prefix string) error {
+	fields := st.Fields.List
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		tag := fieldTag(field, "flag")
+
+		names := make([]string, len(field.Names))
+		for j, name := range field.Names {
+			names[j] = name.Name
+		}
+		embedded := len(names) == 0
+		if embedded {
+			ident, ok := field.Type.(*ast.Ident)
+			if !ok {
+				return fmt.Errorf("embedded field %v is not supported",
+					typeString(field.Type))
+			}
+			names = []string{ident.Name}
+		}
+
+		if len(names) == 1 && names[0] == "_" {
+			if tag != "" {
+				return fmt.Errorf("overriding flag tags are not supported")
+			}
+			continue
+		}
+
+		if strings.Split(tag, ";")[0] == "-" {
+			continue
+		}
+		fTag, err := parseTag(tag)
+		if err != nil {
+			return err
+		}
+
+		// Load Extended Usage.
+		for ; i+1 < len(fields); i++ {
+			next := fields[i+1]
+			use := fieldTag(next, "use")
+			if len(next.Names) != 1 || next.Names[0].Name != "_" ||
+				use == "" {
+				break
+			}
+			if fTag.Usage != "" {
+				fTag.Usage += " "
+			}
+			fTag.Usage += use
+		}
+
+		for _, name := range names {
+			if !ast.IsExported(name) {
+				continue
+			}
+			if err := g.bindField(field, embedded, name,
+				fTag, path, prefix); err != nil {
+				return fmt.Errorf("%v: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) bindField(field *ast.Field, embedded bool, name string,
+	tag flagTag, path, prefix string) error {
+
+	stdName := tag.Name
+	if !tag.HasExplicitName {
+		stdName = flagbind.FromCamelCase(name, flagbind.Separator)
+	}
+	pflagName := stdName
+	if tag.Name == tag.ShortName {
+		pflagName = flagbind.FromCamelCase(name, flagbind.Separator)
+	}
+
+	if ident, ok := field.Type.(*ast.Ident); ok {
+		if st, ok := g.structs[ident.Name]; ok {
+			if tag.Hidden && !g.hidden {
+				g.hidden = true
+				defer func() { g.hidden = false }()
+			}
+			if tag.Flatten || (embedded && !tag.HasExplicitName) {
+				return g.bindStruct(st, path+name+".",
+					joinPrefix(prefix, ""))
+			}
+			return g.bindStruct(st, path+name+".",
+				joinPrefix(prefix, pflagName))
+		}
+	}
+
+	typ := typeString(field.Type)
+	method, ok := varMethods[typ]
+	if !ok {
+		return fmt.Errorf("type %v is not supported", typ)
+	}
+	if typ == "time.Duration" {
+		g.usesTime = true
+	}
+
+	ptr := "&" + path + name
+	value := path + name
+	stdName = strconv.Quote(prefix + stdName)
+	pflagName = strconv.Quote(prefix + pflagName)
+	usage := strconv.Quote(tag.Usage)
+
+	if tag.DefValue != "" {
+		def, err := defaultLiteral(typ, tag.DefValue)
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %v", tag.DefValue, err)
+		}
+		fmt.Fprintf(&g.defaults, "if %v == %v {\n%v = %v\n}\n",
+			value, zeroLiteral(typ), value, def)
+	}
+
+	fmt.Fprintf(&g.std, "fs.%vVar(%v, %v, %v, %v)\n",
+		method, ptr, stdName, value, usage)
+	if tag.HideDefault {
+		fmt.Fprintf(&g.std, "fs.Lookup(%v).DefValue = \"\"\n", stdName)
+	}
+
+	fmt.Fprintf(&g.pflag, "fs.%vVarP(%v, %v, %q, %v, %v)\n",
+		method, ptr, pflagName, tag.ShortName, value, usage)
+	if tag.HideDefault {
+		fmt.Fprintf(&g.pflag, "fs.Lookup(%v).DefValue = \"\"\n", pflagName)
+	}
+	if tag.Hidden || g.hidden {
+		fmt.Fprintf(&g.pflag, "fs.Lookup(%v).Hidden = true\n", pflagName)
+	}
+	return nil
+}
+
+// varMethods maps the supported types to the name of the FlagSet method that
+// defines them, without the Var or VarP suffix.
+var varMethods = map[string]string{
+	"bool":          "Bool",
+	"int":           "Int",
+	"int64":         "Int64",
+	"uint":          "Uint",
+	"uint64":        "Uint64",
+	"float64":       "Float64",
+	"string":        "String",
+	"time.Duration": "Duration",
+}
+
+// defaultLiteral parses the tag <default> for typ and returns it as a Go
+// literal.
+func defaultLiteral(typ, def string) (string, error) {
+	var err error
+	switch typ {
+	case "bool":
+		_, err = strconv.ParseBool(def)
+	case "int", "int64":
+		_, err = strconv.ParseInt(def, 0, 64)
+	case "uint", "uint64":
+		_, err = strconv.ParseUint(def, 0, 64)
+	case "float64":
+		_, err = strconv.ParseFloat(def, 64)
+	case "string":
+		return strconv.Quote(def), nil
+	case "time.Duration":
+		var d time.Duration
+		d, err = time.ParseDuration(def)
+		return fmt.Sprintf("time.Duration(%d)", d), err
+	}
+	return def, err
+}
+
+func zeroLiteral(typ string) string {
+	switch typ {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	}
+	return "0"
+}
+
+// fieldTag returns the value of the struct tag key for field.
+func fieldTag(field *ast.Field, key string) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag).Get(key)
+}
+
+func typeString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// joinPrefix returns prefix + name followed by flagbind.Separator, unless it
+// is empty or already ends with a common separator, as flagbind.Bind does.
+func joinPrefix(prefix, name string) string {
+	joined := prefix + name
+	if joined == "" {
+		return ""
+	}
+	for _, common := range []string{"-", ".", "_"} {
+		if strings.HasSuffix(joined, common) {
+			return joined
+		}
+	}
+	return joined + flagbind.Separator
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSource(t *testing.T, src string) *ast.Package {
+	file, err := parser.ParseFile(token.NewFileSet(), "flags.go", src, 0)
+	require.NoError(t, err)
+	return &ast.Package{
+		Name:  file.Name.Name,
+		Files: map[string]*ast.File{"flags.go": file},
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		Name string
+		Src  string
+		Err  string
+	}{{
+		Name: "not found",
+		Src:  "package p\ntype Other struct{}",
+		Err:  "struct type Flags not found",
+	}, {
+		Name: "invalid default",
+		Src:  "package p\ntype Flags struct{ N int `flag:\";five\"` }",
+		Err:  `Flags: N: invalid default "five": strconv.ParseInt: parsing "five": invalid syntax`,
+	}, {
+		Name: "unsupported type",
+		Src:  "package p\ntype Flags struct{ N []int }",
+		Err:  "Flags: N: type []int is not supported",
+	}, {
+		Name: "unsupported option",
+		Src:  "package p\ntype Flags struct{ N int `flag:\";;;count\"` }",
+		Err:  `Flags: option "count" is not supported by flagbindgen`,
+	}, {
+		Name: "nested error",
+		Src: "package p\ntype Flags struct{ S S }\n" +
+			"type S struct{ D bool `flag:\";maybe\"` }",
+		Err: `Flags: S: D: invalid default "maybe": strconv.ParseBool: parsing "maybe": invalid syntax`,
+	}, {
+		Name: "unsupported list option",
+		Src:  "package p\ntype Flags struct{ N int `flag:\";;;choices=1|2,hidden\"` }",
+		Err:  `Flags: option "choices=1|2" is not supported by flagbindgen`,
+	}, {
+		Name: "empty option",
+		Src:  "package p\ntype Flags struct{ N int `flag:\";;;hidden,,flatten\"` }",
+		Err:  `Flags: empty option: "hidden,,flatten"`,
+	}, {
+		Name: "too many settings",
+		Src:  "package p\ntype Flags struct{ N int `flag:\";;;;\"` }",
+		Err:  "Flags: too many settings: 5",
+	}, {
+		Name: "too many names",
+		Src:  "package p\ntype Flags struct{ N int `flag:\"n,num,x\"` }",
+		Err:  `Flags: too many names: "n,num,x"`,
+	}}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := generate(parseSource(t, test.Src), "Flags")
+			assert.EqualError(t, err, test.Err)
+		})
+	}
+}
+
+func TestJoinPrefix(t *testing.T) {
+	assert.Equal(t, "", joinPrefix("", ""))
+	assert.Equal(t, "db-", joinPrefix("", "db"))
+	assert.Equal(t, "db.", joinPrefix("", "db."))
+	assert.Equal(t, "app-db_", joinPrefix("app-", "db_"))
+	assert.Equal(t, "app-", joinPrefix("app", ""))
+	assert.Equal(t, "app.", joinPrefix("app.", ""))
+}
@@ -0,0 +1,104 @@
+package flagbind
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Collision is a flag name that would be defined by more than one field.
+type Collision struct {
+	FlagName string
+
+	// Fields are the paths of the colliding fields, each beginning with
+	// the type name of its struct.
+	Fields []string
+}
+
+// Collisions reports the flag names that would collide if each of `vs` were
+// bound to the same FlagSet with `opts`. This allows prefixes to be planned
+// before any binding occurs.
+//
+// Each of `vs` must be a pointer to a struct, but only its type is used. A new
+// zero value of each type is bound to its own FlagSet, so the values in `vs`
+// are never modified. An error is returned if any single type cannot be bound
+// on its own.
+//
+// The Collisions are sorted by FlagName.
+func Collisions(vs []interface{}, opts ...Option) ([]Collision, error) {
+	fields := make(map[string][]string)
+	for _, v := range vs {
+		typ := reflect.TypeOf(v)
+		if typ == nil || typ.Kind() != reflect.Ptr {
+			return nil, ErrorInvalidType{v, false}
+		}
+		typeName := typ.Elem().String()
+
+		b := newBind(opts...)
+		b.State = newBindState()
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		if err := b.bind(fs, reflect.New(typ.Elem()).Interface()); err != nil {
+			return nil, fmt.Errorf("%v: %w", typeName, err)
+		}
+		b.State.recordFlags(fs, b.Path)
+
+		for name, path := range b.State.Flags {
+			if path != "" {
+				path = "." + path
+			}
+			fields[name] = append(fields[name], typeName+path)
+		}
+	}
+
+	var collisions []Collision
+	for name, paths := range fields {
+		if len(paths) > 1 {
+			collisions = append(collisions, Collision{name, paths})
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].FlagName < collisions[j].FlagName
+	})
+	return collisions, nil
+}
+
+// Rename is a flag that was renamed by the RenameCollisions Option.
+type Rename struct {
+	// FlagName is the name that collided, and NewName is the name that
+	// the field at the dotted path FieldName was bound to instead.
+	FlagName, NewName, FieldName string
+}
+
+// collides reports whether the flag name has already been bound, or is already
+// defined in fs and would not be skipped or overridden.
+func (b bind) collides(fs FlagSet, name string) bool {
+	if _, ok := b.State.Flags[name]; ok {
+		return true
+	}
+	return !b.SkipExisting && !b.OverrideExisting &&
+		flagValue(fs, name) != nil
+}
+
+// pathFlagName returns the flag name prefixed by the struct field path of the
+// struct that contains the field at path, such as "client-timeout" for the
+// "timeout" flag of "Client.Timeout", or "" if the field is not nested.
+func (b bind) pathFlagName(path, name string) string {
+	i := strings.LastIndex(path, ".")
+	if i < 0 {
+		return ""
+	}
+	sep := b.separator()
+	parent := strings.NewReplacer("[", ".", "]", "").Replace(path[:i])
+	var sb strings.Builder
+	for _, part := range strings.Split(parent, ".") {
+		sb.WriteString(FromCamelCase(part, sep))
+		sb.WriteString(sep)
+	}
+	sb.WriteString(name)
+	return sb.String()
+}
@@ -0,0 +1,99 @@
+package flagbind
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type CollisionA struct {
+	Timeout time.Duration
+	Custom  bool
+}
+
+type CollisionB struct {
+	Nested struct {
+		Timeout time.Duration
+	} `flag:";;;flatten"`
+	StructA
+}
+
+type CollisionInvalid struct {
+	Timeout time.Duration
+	Client  http.Client `flag:";;;flatten"`
+}
+
+func TestCollisions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, err := Collisions([]interface{}{&CollisionInvalid{}})
+	require.EqualError(err, "flagbind.CollisionInvalid: "+
+		`Timeout and Client.Timeout both map to "timeout"`)
+
+	a := &CollisionA{Timeout: time.Second}
+	collisions, err := Collisions([]interface{}{
+		a, &CollisionB{}, &StructA{}, &ValidTestFlags{}})
+	require.NoError(err)
+	assert.Equal([]Collision{{
+		FlagName: "custom",
+		Fields: []string{
+			"flagbind.CollisionA.Custom",
+			"flagbind.ValidTestFlags",
+		},
+	}, {
+		FlagName: "struct-a-bool",
+		Fields: []string{
+			"flagbind.CollisionB.StructA.StructABool",
+			"flagbind.StructA.StructABool",
+			"flagbind.ValidTestFlags.StructA.StructABool",
+		},
+	}, {
+		FlagName: "timeout",
+		Fields: []string{
+			"flagbind.CollisionA.Timeout",
+			"flagbind.CollisionB.Nested.Timeout",
+		},
+	}}, collisions)
+
+	assert.Equal(time.Second, a.Timeout, "value modified")
+
+	_, err = Collisions([]interface{}{CollisionA{}})
+	assert.EqualError(err, ErrorInvalidType{CollisionA{}, false}.Error())
+}
+
+func TestRenameCollisions(t *testing.T) {
+	var f struct {
+		Timeout time.Duration
+		Client  struct {
+			Timeout time.Duration
+			Retries int
+		} `flag:";;;flatten"`
+		Servers []struct {
+			Retries int
+		} `flag:"srv;;;len=1"`
+	}
+	var renames []Rename
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.Int("srv-0-retries", 0, "")
+	require.NoError(t, Bind(fs, &f, RenameCollisions(func(r Rename) {
+		renames = append(renames, r)
+	})))
+	assert.Equal(t, []Rename{
+		{"timeout", "client-timeout", "Client.Timeout"},
+		{"srv-0-retries", "servers-0-srv-0-retries", "Servers[0].Retries"},
+	}, renames)
+
+	require.NoError(t, fs.Parse([]string{"--client-timeout", "1s"}))
+	assert.Equal(t, time.Second, f.Client.Timeout)
+
+	var g struct{ Verbose bool }
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.Bool("verbose", false, "")
+	assert.EqualError(t, Bind(fs, &g, RenameCollisions(nil)),
+		"flag redefined: verbose", "top level fields are not renamed")
+}
@@ -0,0 +1,247 @@
+package flagbind
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+)
+
+// Runner is implemented by the flag struct of a command that runs. Run is
+// called with the arguments that remain after the flags are parsed. See
+// Commands.
+type Runner interface {
+	Run(args []string) error
+}
+
+// Commands dispatches to subcommands, each with its own flags, without
+// adopting a larger framework. For example:
+//
+//      type Add struct {
+//              Force bool
+//      }
+//
+//      func (a *Add) Run(args []string) error { ... }
+//
+//      cmds := flagbind.BindCommands(map[string]interface{}{
+//              "add":    &Add{},
+//              "remove": &Remove{},
+//      })
+//      if err := cmds.Run(os.Args[1:]); err != nil {
+//              ...
+//              os.Exit(flagbind.ExitCode(err))
+//      }
+//
+// Any flags that precede the command must be parsed by the caller, which then
+// passes the remaining args to Run.
+type Commands struct {
+	// Name is the name of the program, or of the parent command, used in
+	// the usage and for the name of each FlagSet.
+	Name string
+
+	// Commands maps each command name to a pointer to its flag struct,
+	// which is bound with Bind and may implement Runner, or to another
+	// *Commands for nested subcommands, which inherits any of Options,
+	// Layers, NewFlagSet, and Output that it does not set.
+	Commands map[string]interface{}
+
+	// Options are passed to Bind for each command.
+	Options []Option
+
+	// Layers are applied to the flags of each command with Resolve after
+	// they are parsed, such as EnvLayer and ConfigLayer.
+	Layers []Layer
+
+	// NewFlagSet returns a new FlagSet for the command with the given
+	// name. The default returns a *flag.FlagSet with ContinueOnError.
+	NewFlagSet func(name string) FlagSet
+
+	// Output is where the usage is written, including the usage and
+	// errors written by the FlagSet of each command if it has a SetOutput
+	// method, as *flag.FlagSet and *pflag.FlagSet do. The default is
+	// os.Stderr.
+	Output io.Writer
+}
+
+// BindCommands returns Commands for cmds, named after os.Args[0], that bind the
+// flags of each command with opts.
+func BindCommands(cmds map[string]interface{}, opts ...Option) *Commands {
+	return &Commands{Name: os.Args[0], Commands: cmds, Options: opts}
+}
+
+// Run binds and parses the flags of the command named by args[0] with the
+// rest of args, using Parse, and then calls Run with the remaining arguments
+// if its flag struct implements Runner.
+func (c *Commands) Run(args []string) error {
+	_, v, args, err := c.Parse(args)
+	if err != nil {
+		return err
+	}
+	if r, ok := v.(Runner); ok {
+		return r.Run(args)
+	}
+	return nil
+}
+
+// Parse binds and parses the flags of the command named by args[0] with the
+// rest of args, applies the Layers with Resolve, and checks the flags with
+// Validate. It returns the name of the command, its flag struct, and the
+// remaining arguments. For nested Commands, the name is the path of command
+// names joined with spaces, such as "remote add".
+//
+// If args is empty, or args[0] is "help", "-h" or "--help", the usage is
+// written to Output, and Parse returns ErrorMissingCommand or flag.ErrHelp.
+// If the command is not defined, the usage is written and Parse returns
+// ErrorUnknownCommand. If the flags cannot be parsed, Parse returns
+// ErrorParse, unless help was requested, and if a Layer cannot be applied,
+// ErrorResolve. See ExitCode.
+func (c *Commands) Parse(args []string) (string, interface{}, []string,
+	error) {
+	if len(args) == 0 {
+		c.usage()
+		return "", nil, nil, ErrorMissingCommand
+	}
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		c.usage()
+		return "", nil, nil, flag.ErrHelp
+	}
+	v, ok := c.Commands[name]
+	if !ok {
+		c.usage()
+		return "", nil, nil, ErrorUnknownCommand{name}
+	}
+	fullName := c.Name + " " + name
+
+	// Nested Commands inherit any settings that they do not set.
+	if sub, ok := v.(*Commands); ok {
+		sub := *sub
+		if sub.Name == "" {
+			sub.Name = fullName
+		}
+		if sub.Options == nil {
+			sub.Options = c.Options
+		}
+		if sub.Layers == nil {
+			sub.Layers = c.Layers
+		}
+		if sub.NewFlagSet == nil {
+			sub.NewFlagSet = c.NewFlagSet
+		}
+		if sub.Output == nil {
+			sub.Output = c.Output
+		}
+		subName, v, args, err := sub.Parse(args[1:])
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return name + " " + subName, v, args, nil
+	}
+
+	newFlagSet := c.NewFlagSet
+	if newFlagSet == nil {
+		newFlagSet = func(name string) FlagSet {
+			return flag.NewFlagSet(name, flag.ContinueOnError)
+		}
+	}
+	fs := newFlagSet(fullName)
+	if fs, ok := fs.(interface{ SetOutput(io.Writer) }); ok && c.Output != nil {
+		fs.SetOutput(c.Output)
+	}
+	if err := Bind(fs, v, c.Options...); err != nil {
+		return "", nil, nil, err
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp || err == pflag.ErrHelp {
+			return "", nil, nil, err
+		}
+		return "", nil, nil, ErrorParse{fullName, err}
+	}
+	if err := Resolve(fs, c.Layers...); err != nil {
+		return "", nil, nil, err
+	}
+	if err := Validate(fs, v, c.Options...); err != nil {
+		return "", nil, nil, err
+	}
+	return name, v, fs.Args(), nil
+}
+
+// The exit codes returned by ExitCode, so that scripts may tell the kinds of
+// failure apart.
+const (
+	// ExitOK is for no error, or a request for help.
+	ExitOK = 0
+
+	// ExitError is for any other error, such as from Runner.Run.
+	ExitError = 1
+
+	// ExitUsage is for an invalid command line, as with
+	// flag.ExitOnError.
+	ExitUsage = 2
+
+	// ExitValidation is for a failed `requires`, `conflicts`, `oneof` or
+	// `at-most-one` constraint.
+	ExitValidation = 3
+
+	// ExitConfig is for a value from a config file or secret resolver
+	// that cannot be looked up or set.
+	ExitConfig = 4
+
+	// ExitEnv is for a value from an environment variable that cannot be
+	// set.
+	ExitEnv = 5
+)
+
+// ExitCode returns the exit code for an error returned by Commands.Run or
+// Commands.Parse, such as ExitUsage for ErrorParse, ErrorMissingCommand and
+// ErrorUnknownCommand, ExitValidation for an error from Validate, and
+// ExitConfig or ExitEnv for ErrorResolve, depending on its Origin. Errors are
+// unwrapped, so ExitCode also classifies the errors of Validate and Resolve
+// when they are called directly.
+func ExitCode(err error) int {
+	var resolveErr ErrorResolve
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp),
+		errors.Is(err, pflag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &resolveErr):
+		switch resolveErr.Origin {
+		case OriginConfig, OriginSecret:
+			return ExitConfig
+		case OriginEnv:
+			return ExitEnv
+		}
+	case errors.As(err, &ErrorParse{}),
+		errors.Is(err, ErrorMissingCommand),
+		errors.As(err, &ErrorUnknownCommand{}):
+		return ExitUsage
+	case errors.As(err, &ErrorFlagRequires{}),
+		errors.As(err, &ErrorFlagConflicts{}),
+		errors.As(err, &ErrorFlagOneOf{}):
+		return ExitValidation
+	}
+	return ExitError
+}
+
+// usage writes the usage listing the command names to Output.
+func (c *Commands) usage() {
+	out := c.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	names := make([]string, 0, len(c.Commands))
+	for name := range c.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(out, "Usage: %v <command> [flags] [args]\n\nCommands:\n",
+		c.Name)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %v\n", name)
+	}
+}
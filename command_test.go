@@ -0,0 +1,169 @@
+package flagbind
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addCommand struct {
+	Force bool
+	Args  []string `flag:"-"`
+}
+
+func (a *addCommand) Run(args []string) error {
+	if len(args) == 0 {
+		return errors.New("nothing to add")
+	}
+	a.Args = args
+	return nil
+}
+
+func TestCommands(t *testing.T) {
+	var add addCommand
+	var remote struct{ URL string }
+	var usage bytes.Buffer
+	cmds := BindCommands(map[string]interface{}{
+		"add": &add,
+		"remote": &Commands{Commands: map[string]interface{}{
+			"set-url": &remote,
+		}},
+	})
+	cmds.Name = "app"
+	cmds.Output = &usage
+	cmds.NewFlagSet = func(name string) FlagSet {
+		return pflag.NewFlagSet(name, pflag.ContinueOnError)
+	}
+
+	require.NoError(t, cmds.Run([]string{"add", "--force", "a", "b"}))
+	assert.True(t, add.Force)
+	assert.Equal(t, []string{"a", "b"}, add.Args)
+	assert.EqualError(t, cmds.Run([]string{"add"}), "nothing to add")
+
+	name, v, args, err := cmds.Parse([]string{
+		"remote", "set-url", "--url", "https://example.com", "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "remote set-url", name)
+	assert.Equal(t, &remote, v)
+	assert.Equal(t, []string{"x"}, args)
+	assert.Equal(t, "https://example.com", remote.URL)
+
+	assert.Equal(t, ErrorMissingCommand, cmds.Run(nil))
+	assert.Equal(t, `Usage: app <command> [flags] [args]
+
+Commands:
+  add
+  remote
+`, usage.String())
+
+	usage.Reset()
+	assert.Equal(t, ErrorUnknownCommand{"rm"}, cmds.Run([]string{"rm"}))
+	assert.EqualError(t, cmds.Run([]string{"rm"}), `unknown command: "rm"`)
+
+	usage.Reset()
+	assert.Equal(t, flag.ErrHelp, cmds.Run([]string{"remote", "--help"}))
+	assert.Equal(t, `Usage: app remote <command> [flags] [args]
+
+Commands:
+  set-url
+`, usage.String())
+
+	usage.Reset()
+	add = addCommand{}
+	assert.Equal(t, pflag.ErrHelp, cmds.Run([]string{"add", "--help"}))
+	assert.Equal(t, `Usage of app add:
+      --force   
+`, usage.String())
+
+	usage.Reset()
+	cmds.NewFlagSet = nil
+	err = cmds.Run([]string{"add", "-unknown"})
+	assert.IsType(t, ErrorParse{}, err)
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	assert.Equal(t, `flag provided but not defined: -unknown
+Usage of app add:
+  -force
+    	
+`, usage.String())
+}
+
+func TestExitCode(t *testing.T) {
+	type serve struct {
+		Port int  `flag:";80"`
+		TLS  bool `flag:";;;requires=cert"`
+		Cert string
+	}
+	newCommands := func(layers ...Layer) *Commands {
+		return &Commands{
+			Name:     "app",
+			Commands: map[string]interface{}{"serve": &serve{}},
+			Layers:   layers,
+			Output:   ioutil.Discard,
+		}
+	}
+	env := func(value string) Layer {
+		return Layer{Origin: OriginEnv,
+			Lookup: func(string) (string, bool, error) {
+				return value, true, nil
+			}}
+	}
+
+	tests := []struct {
+		Name string
+		Cmds *Commands
+		Args []string
+		Code int
+	}{{
+		Name: "ok",
+		Cmds: newCommands(),
+		Args: []string{"serve", "-port", "8080"},
+		Code: ExitOK,
+	}, {
+		Name: "help",
+		Cmds: newCommands(),
+		Args: []string{"serve", "-h"},
+		Code: ExitOK,
+	}, {
+		Name: "missing command",
+		Cmds: newCommands(),
+		Code: ExitUsage,
+	}, {
+		Name: "unknown command",
+		Cmds: newCommands(),
+		Args: []string{"rm"},
+		Code: ExitUsage,
+	}, {
+		Name: "invalid flag",
+		Cmds: newCommands(),
+		Args: []string{"serve", "-port", "x"},
+		Code: ExitUsage,
+	}, {
+		Name: "validation",
+		Cmds: newCommands(),
+		Args: []string{"serve", "-tls"},
+		Code: ExitValidation,
+	}, {
+		Name: "config",
+		Cmds: newCommands(ConfigLayer(map[string]string{"port": "x"})),
+		Args: []string{"serve"},
+		Code: ExitConfig,
+	}, {
+		Name: "env",
+		Cmds: newCommands(env("x")),
+		Args: []string{"serve", "-tls", "-cert", "c"},
+		Code: ExitEnv,
+	}}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := test.Cmds.Run(test.Args)
+			assert.Equal(t, test.Code, ExitCode(err), err)
+		})
+	}
+	assert.Equal(t, ExitError, ExitCode(errors.New("other")))
+}
@@ -0,0 +1,255 @@
+package flagbind
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Completion is the metadata for a shell completion script.
+type Completion struct {
+	// Name of the command to complete. If empty, the <name> of a Command
+	// Tag is used. See WriteManPage.
+	Name string
+
+	// STDFlag completes the flags as they are used with the standard flag
+	// package, with a single dash and no short names, instead of pflag.
+	STDFlag bool
+}
+
+// completionFlag is a flag as it is completed by a shell.
+type completionFlag struct {
+	Name, Short string
+	Usage       string
+
+	// TakesValue is false for flags such as bool flags that may be used
+	// without a value.
+	TakesValue bool
+	Choices    []string
+	// Hint is "file" or "dir" for File and Dir flags.
+	Hint string
+}
+
+// completionFlags returns the visible flags that Bind would define for v, in
+// the order they are declared, and sets the command name.
+func completionFlags(v interface{}, cmd *Completion,
+	opts []Option) ([]completionFlag, error) {
+	infos, err := Inspect(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Name == "" {
+		cmd.Name, _ = commandTag(v)
+	}
+	if cmd.Name == "" {
+		return nil, fmt.Errorf("no command name for completion")
+	}
+
+	var flags []completionFlag
+	for _, info := range infos {
+		if info.Hidden {
+			continue
+		}
+		flag := completionFlag{
+			Name:       info.Name,
+			Usage:      strings.Join(strings.Fields(info.Usage), " "),
+			TakesValue: info.NoOptDefVal == "",
+		}
+		if choices, ok := info.Option("choices"); ok {
+			flag.Choices = splitList(choices)
+		}
+		if !cmd.STDFlag {
+			flag.Short = info.ShortName
+		}
+		switch info.Type {
+		case "file", "dir":
+			flag.Hint = info.Type
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// dashes returns the prefix used by long flag names.
+func (cmd Completion) dashes() string {
+	if cmd.STDFlag {
+		return "-"
+	}
+	return "--"
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// WriteBashCompletion writes a bash completion script for the flags that Bind
+// would define for v, with the given opts, to w. Flags with the `choices`
+// option complete their choices, and File and Dir flags complete file and
+// directory names.
+func WriteBashCompletion(w io.Writer, v interface{}, cmd Completion,
+	opts ...Option) error {
+	flags, err := completionFlags(v, &cmd, opts)
+	if err != nil {
+		return err
+	}
+	function := "_" + nonIdentifier.ReplaceAllString(cmd.Name, "_") +
+		"_completion"
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# bash completion for %v\n\n", cmd.Name)
+	fmt.Fprintf(bw, "%v() {\n", function)
+	fmt.Fprintf(bw, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(bw, "    local prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(bw, "    if [[ \"$cur\" == -*=* ]]; then\n")
+	fmt.Fprintf(bw, "        prev=\"${cur%%%%=*}\"\n")
+	fmt.Fprintf(bw, "        cur=\"${cur#*=}\"\n")
+	fmt.Fprintf(bw, "    fi\n\n")
+
+	fmt.Fprintf(bw, "    case \"$prev\" in\n")
+	for _, flag := range flags {
+		if !flag.TakesValue {
+			continue
+		}
+		fmt.Fprintf(bw, "    %v%v", cmd.dashes(), flag.Name)
+		if flag.Short != "" {
+			fmt.Fprintf(bw, "|-%v", flag.Short)
+		}
+		fmt.Fprintf(bw, ")\n")
+		switch {
+		case len(flag.Choices) > 0:
+			fmt.Fprintf(bw, "        COMPREPLY=($(compgen -W %v -- \"$cur\"))\n",
+				shellQuote(strings.Join(flag.Choices, " ")))
+		case flag.Hint == "file":
+			fmt.Fprintf(bw, "        COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		case flag.Hint == "dir":
+			fmt.Fprintf(bw, "        COMPREPLY=($(compgen -d -- \"$cur\"))\n")
+		default:
+			fmt.Fprintf(bw, "        COMPREPLY=()\n")
+		}
+		fmt.Fprintf(bw, "        return\n        ;;\n")
+	}
+	fmt.Fprintf(bw, "    esac\n\n")
+
+	var names []string
+	for _, flag := range flags {
+		names = append(names, cmd.dashes()+flag.Name)
+		if flag.Short != "" {
+			names = append(names, "-"+flag.Short)
+		}
+	}
+	fmt.Fprintf(bw, "    if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(bw, "        COMPREPLY=($(compgen -W %v -- \"$cur\"))\n",
+		shellQuote(strings.Join(names, " ")))
+	fmt.Fprintf(bw, "    fi\n")
+	fmt.Fprintf(bw, "}\n\n")
+	fmt.Fprintf(bw, "complete -F %v %v\n", function, cmd.Name)
+
+	return bw.Flush()
+}
+
+// shellQuote single quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// WriteZshCompletion writes a zsh completion script for the flags that Bind
+// would define for v, with the given opts, to w. See WriteBashCompletion.
+func WriteZshCompletion(w io.Writer, v interface{}, cmd Completion,
+	opts ...Option) error {
+	flags, err := completionFlags(v, &cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "#compdef %v\n\n", cmd.Name)
+	fmt.Fprintf(bw, "_arguments")
+	for _, flag := range flags {
+		long := cmd.dashes() + flag.Name
+		if flag.TakesValue {
+			long += "="
+		}
+		spec := fmt.Sprintf("[%v]", zshEscape(flag.Usage))
+		if flag.TakesValue {
+			spec += ":" + zshEscape(flag.Name) + ":"
+			switch {
+			case len(flag.Choices) > 0:
+				choices := make([]string, len(flag.Choices))
+				for i, choice := range flag.Choices {
+					choices[i] = zshChoiceEscaper.Replace(
+						zshEscape(choice))
+				}
+				spec += "(" + strings.Join(choices, " ") + ")"
+			case flag.Hint == "file":
+				spec += "_files"
+			case flag.Hint == "dir":
+				spec += "_files -/"
+			}
+		}
+		fmt.Fprintf(bw, " \\\n    ")
+		if flag.Short != "" {
+			fmt.Fprintf(bw, "'(-%v %v%v)'{-%v,%v}%v", flag.Short,
+				cmd.dashes(), flag.Name, flag.Short, long,
+				shellQuote(spec))
+			continue
+		}
+		fmt.Fprintf(bw, "%v", shellQuote(long+spec))
+	}
+	fmt.Fprintf(bw, "\n")
+
+	return bw.Flush()
+}
+
+// zshEscape escapes the characters that are special in an _arguments spec.
+func zshEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, `[`, `\[`, `]`, `\]`, `:`, `\:`,
+	).Replace(s)
+}
+
+// zshChoiceEscaper escapes the additional characters that are special in an
+// _arguments list of choices.
+var zshChoiceEscaper = strings.NewReplacer(`(`, `\(`, `)`, `\)`, ` `, `\ `)
+
+// WriteFishCompletion writes a fish completion script for the flags that Bind
+// would define for v, with the given opts, to w. See WriteBashCompletion.
+func WriteFishCompletion(w io.Writer, v interface{}, cmd Completion,
+	opts ...Option) error {
+	flags, err := completionFlags(v, &cmd, opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, flag := range flags {
+		fmt.Fprintf(bw, "complete -c %v", cmd.Name)
+		if flag.Short != "" {
+			fmt.Fprintf(bw, " -s %v", flag.Short)
+		}
+		if cmd.STDFlag {
+			fmt.Fprintf(bw, " -o %v", flag.Name)
+		} else {
+			fmt.Fprintf(bw, " -l %v", flag.Name)
+		}
+		if flag.Usage != "" {
+			fmt.Fprintf(bw, " -d %v", shellQuote(flag.Usage))
+		}
+		if flag.TakesValue {
+			switch {
+			case len(flag.Choices) > 0:
+				fmt.Fprintf(bw, " -x -a %v",
+					shellQuote(strings.Join(flag.Choices, " ")))
+			case flag.Hint == "file":
+				fmt.Fprintf(bw, " -r -F")
+			case flag.Hint == "dir":
+				fmt.Fprintf(bw, " -x -a '(__fish_complete_directories)'")
+			default:
+				fmt.Fprintf(bw, " -x")
+			}
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,100 @@
+package flagbind
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type completionTestFlags struct {
+	_       struct{} `command:"my-app;Demo application"`
+	Format  string   `flag:"format,f;json;Output format;choices=json|yaml"`
+	Config  File     `flag:";;Config file"`
+	Out     Dir      `flag:";;Output directory"`
+	Name    string   `flag:";;Name with 'quotes'"`
+	Verbose bool     `flag:";;Verbose output"`
+	Hidden  bool     `flag:";;;hidden"`
+}
+
+func TestWriteBashCompletion(t *testing.T) {
+	var f completionTestFlags
+	var script bytes.Buffer
+	require.NoError(t, WriteBashCompletion(&script, &f, Completion{}))
+	assert.Equal(t, `# bash completion for my-app
+
+_my_app_completion() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    if [[ "$cur" == -*=* ]]; then
+        prev="${cur%%=*}"
+        cur="${cur#*=}"
+    fi
+
+    case "$prev" in
+    --format|-f)
+        COMPREPLY=($(compgen -W 'json yaml' -- "$cur"))
+        return
+        ;;
+    --config)
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+        ;;
+    --out)
+        COMPREPLY=($(compgen -d -- "$cur"))
+        return
+        ;;
+    --name)
+        COMPREPLY=()
+        return
+        ;;
+    esac
+
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W '--format -f --config --out --name --verbose' -- "$cur"))
+    fi
+}
+
+complete -F _my_app_completion my-app
+`, script.String())
+
+	script.Reset()
+	require.NoError(t, WriteBashCompletion(&script, &f,
+		Completion{Name: "app", STDFlag: true}))
+	assert.Contains(t, script.String(), "    -format)\n")
+	assert.Contains(t, script.String(), "complete -F _app_completion app\n")
+}
+
+func TestWriteZshCompletion(t *testing.T) {
+	var f completionTestFlags
+	var script bytes.Buffer
+	require.NoError(t, WriteZshCompletion(&script, &f, Completion{}))
+	assert.Equal(t, `#compdef my-app
+
+_arguments \
+    '(-f --format)'{-f,--format=}'[Output format]:format:(json yaml)' \
+    '--config=[Config file]:config:_files' \
+    '--out=[Output directory]:out:_files -/' \
+    '--name=[Name with '\''quotes'\'']:name:' \
+    '--verbose[Verbose output]'
+`, script.String())
+}
+
+func TestWriteFishCompletion(t *testing.T) {
+	var f completionTestFlags
+	var script bytes.Buffer
+	require.NoError(t, WriteFishCompletion(&script, &f, Completion{}))
+	assert.Equal(t, `complete -c my-app -s f -l format -d 'Output format' -x -a 'json yaml'
+complete -c my-app -l config -d 'Config file' -r -F
+complete -c my-app -l out -d 'Output directory' -x -a '(__fish_complete_directories)'
+complete -c my-app -l name -d 'Name with '\''quotes'\''' -x
+complete -c my-app -l verbose -d 'Verbose output'
+`, script.String())
+
+	script.Reset()
+	require.NoError(t, WriteFishCompletion(&script, &f,
+		Completion{STDFlag: true}))
+	assert.Contains(t, script.String(),
+		"complete -c my-app -o format -d 'Output format' -x -a 'json yaml'\n")
+}
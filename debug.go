@@ -0,0 +1,42 @@
+package flagbind
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler returns an http.Handler that serves the Effective Settings of fs as
+// a JSON array of objects with "name", "value", and "origin" keys, so that the
+// configuration that a running program is actually using may be inspected.
+// Values are displayed as by Effective, so Secrets and `sensitive` flags are
+// redacted.
+func Handler(fs FlagSet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(Effective(fs))
+	})
+}
+
+// Var returns an expvar.Var whose String is the JSON of the Effective Settings
+// of fs, in the same format as Handler, so that they may be served on
+// /debug/vars.
+//
+//      expvar.Publish("flags", flagbind.Var(fs))
+//
+// This package does not import expvar, so that it does not register its
+// handler on http.DefaultServeMux unless the program does.
+func Var(fs FlagSet) interface{ String() string } {
+	return settingsVar{fs}
+}
+
+type settingsVar struct{ fs FlagSet }
+
+func (v settingsVar) String() string {
+	data, err := json.Marshal(Effective(v.fs))
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
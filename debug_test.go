@@ -0,0 +1,49 @@
+package flagbind
+
+import (
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	var f struct {
+		Host     string `flag:";localhost"`
+		Password Secret
+		Port     int
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--password", "hunter2"}))
+	require.NoError(t, SetFrom(fs, "port", "80", OriginEnv))
+
+	const want = `[
+  {
+    "name": "host",
+    "value": "localhost",
+    "origin": "default"
+  },
+  {
+    "name": "password",
+    "value": "***",
+    "origin": "flag"
+  },
+  {
+    "name": "port",
+    "value": "80",
+    "origin": "env"
+  }
+]
+`
+	rec := httptest.NewRecorder()
+	Handler(fs).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, want, rec.Body.String())
+
+	expvar.Publish("flagbind-test", Var(fs))
+	assert.JSONEq(t, want, expvar.Get("flagbind-test").String())
+}
@@ -0,0 +1,130 @@
+package flagbind
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/spf13/pflag"
+)
+
+// Format is an output format for DumpValues.
+type Format int
+
+// The Formats supported by DumpValues.
+const (
+	FormatJSON Format = iota
+	FormatYAML
+)
+
+// DumpValues returns the current values of the fields of the struct that v
+// points to, keyed by the flag names that Bind would define for v with the
+// given opts, in the order that the flags would be defined, such as for a
+// --print-config flag. No defaults are applied, and v is not modified.
+//
+// Each value is the text of its flag Value, as a string, except that slices are
+// lists of strings, so that the JSON output may be read back with
+// ReadJSONConfig. The YAML output is a flat mapping with the same values.
+// Values are displayed as by Effective, so Secrets and `sensitive` flags are
+// redacted.
+func DumpValues(v interface{}, format Format, opts ...Option) ([]byte, error) {
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	b := newBind(opts...)
+	b.Values = nil
+	b.ValueSource = nil
+	b.NoDefaults = true
+	b.setNormalizeFunc(fs)
+	if err := b.bind(fs, copyStruct(v)); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatJSON:
+		buf.WriteString("{")
+	case FormatYAML:
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+	for _, name := range b.State.Order {
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		var value interface{} = f.Value.String()
+		if !b.State.Tags[name].Sensitive {
+			base := baseValue(f.Value)
+			value = base.String()
+			if slice, ok := base.(pflag.SliceValue); ok {
+				value = slice.GetSlice()
+			}
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		key, _ := json.Marshal(name)
+		switch format {
+		case FormatJSON:
+			if buf.Len() > 1 {
+				buf.WriteString(",")
+			}
+			fmt.Fprintf(&buf, "\n  %s: %s", key, data)
+		case FormatYAML:
+			if yamlPlainKey.MatchString(name) {
+				key = []byte(name)
+			}
+			fmt.Fprintf(&buf, "%s: %s\n", key, data)
+		}
+	}
+	if format == FormatJSON {
+		if buf.Len() > 1 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("}\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlPlainKey matches flag names that may be written as plain YAML keys.
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// baseValue returns the Value wrapped by any tag options of a flag. Take care
+// that a `sensitive` Value is not displayed.
+func baseValue(v flag.Value) flag.Value {
+	for {
+		w := unwrapValue(v)
+		if w == nil {
+			return v
+		}
+		v = w
+	}
+}
+
+// unwrapValue returns the Value wrapped by v, or nil if v is not a wrapper.
+func unwrapValue(v flag.Value) flag.Value {
+	switch w := v.(type) {
+	case transformValue:
+		return w.Value
+	case afterSetValue:
+		return w.Value
+	case lockedValue:
+		return w.Value
+	case zeroDefaultValue:
+		return w.Value
+	case sensitiveValue:
+		return w.Value
+	case *originValue:
+		return w.Value
+	case originBoolValue:
+		return w.Value
+	case boolFlagValue:
+		return w.Value
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package flagbind
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dumpFlags struct {
+	Host     string `flag:";localhost"`
+	Port     int    `flag:";80"`
+	Password Secret
+	HTTP     struct {
+		Timeout time.Duration
+		Headers []string `flag:"header"`
+	}
+	Verbose bool
+}
+
+func (f *dumpFlags) SetDefaults() { f.Verbose = true }
+
+func TestDumpValues(t *testing.T) {
+	var f dumpFlags
+	f.Port = 8080
+	f.Password = "hunter2"
+	f.HTTP.Timeout = 5 * time.Second
+	f.HTTP.Headers = []string{"a: 1", "b: 2"}
+
+	data, err := DumpValues(&f, FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "host": "",
+  "port": "8080",
+  "password": "***",
+  "http-timeout": "5s",
+  "http-header": ["a: 1","b: 2"],
+  "verbose": "false"
+}
+`, string(data))
+	assert.Equal(t, dumpFlags{Port: 8080, Password: "hunter2",
+		HTTP: f.HTTP}, f, "v is not modified")
+
+	config, err := ReadJSONConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1,b: 2", config["http-header"])
+
+	data, err = DumpValues(&f, FormatYAML, Prefix("app."))
+	require.NoError(t, err)
+	assert.Equal(t, `app.host: ""
+app.port: "8080"
+app.password: "***"
+app.http-timeout: "5s"
+app.http-header: ["a: 1","b: 2"]
+app.verbose: "false"
+`, string(data))
+
+	data, err = DumpValues(&struct{}{}, FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	_, err = DumpValues(&f, Format(-1))
+	assert.EqualError(t, err, "unknown format: -1")
+}
+
+func TestDumpValuesHideZeroDefaults(t *testing.T) {
+	var f struct {
+		Count int       `flag:";;;skip-zero-default"`
+		Rate  float64   `flag:";;;skip-zero-default"`
+		Sizes []float64 `flag:";;;skip-zero-default"`
+	}
+	data, err := DumpValues(&f, FormatJSON, HideZeroDefaults())
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "count": "0",
+  "rate": "0",
+  "sizes": []
+}
+`, string(data))
+}
@@ -0,0 +1,97 @@
+package flagbind
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration is like time.ParseDuration, but also accepts the units "d" for
+// days of 24 hours and "w" for weeks of 7 days, in any combination, such as
+// "1w2d" or "1.5d12h". See the ExtendedDurations Option.
+func ParseDuration(text string) (time.Duration, error) {
+	s := text
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+
+	// Days and weeks are summed here, and any other units are passed on to
+	// time.ParseDuration.
+	var days float64
+	var rest strings.Builder
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i < 0 {
+			i = len(s)
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", text)
+		}
+		num := s[:i]
+		j := strings.IndexFunc(s[i:], func(r rune) bool {
+			return r >= '0' && r <= '9' || r == '.'
+		})
+		if j < 0 {
+			j = len(s) - i
+		}
+		unit := s[i : i+j]
+		s = s[i+j:]
+
+		switch unit {
+		case "d", "w":
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", text)
+			}
+			if unit == "w" {
+				n *= 7
+			}
+			days += n
+		default:
+			rest.WriteString(num)
+			rest.WriteString(unit)
+		}
+	}
+
+	var d time.Duration
+	if rest.Len() > 0 {
+		var err error
+		if d, err = time.ParseDuration(rest.String()); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", text)
+		}
+	}
+	total := days*float64(24*time.Hour) + float64(d)
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid duration %q", text)
+	}
+	if neg {
+		return -time.Duration(total), nil
+	}
+	return time.Duration(total), nil
+}
+
+// durationValue is a time.Duration flag.Value that is parsed with
+// ParseDuration.
+type durationValue time.Duration
+
+func (d *durationValue) Set(text string) error {
+	v, err := ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = durationValue(v)
+	return nil
+}
+
+func (d durationValue) String() string { return time.Duration(d).String() }
+
+func (d durationValue) Type() string { return "duration" }
@@ -0,0 +1,59 @@
+package flagbind
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	const day = 24 * time.Hour
+	for text, want := range map[string]time.Duration{
+		"1d":      day,
+		"1w2d":    9 * day,
+		"1.5d12h": 2 * day,
+		"-2w":     -14 * day,
+		"90m":     90 * time.Minute,
+		"1h30m":   90 * time.Minute,
+		"0":       0,
+		"+1d1ms":  day + time.Millisecond,
+	} {
+		d, err := ParseDuration(text)
+		if assert.NoError(t, err, text) {
+			assert.Equal(t, want, d, text)
+		}
+	}
+	for _, text := range []string{"", "-", "d", "1", "1x", "1d-2h",
+		"..d", "99999999w"} {
+		_, err := ParseDuration(text)
+		assert.EqualError(t, err, `invalid duration "`+text+`"`)
+	}
+}
+
+func TestExtendedDurations(t *testing.T) {
+	var f struct {
+		Retention time.Duration `flag:";1w"`
+		Expiry    time.Duration
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, ExtendedDurations()))
+	assert.Equal(t, 7*24*time.Hour, f.Retention)
+	assert.Equal(t, "1w", fs.Lookup("retention").DefValue)
+	require.NoError(t, fs.Parse([]string{"--expiry", "2d"}))
+	assert.Equal(t, 48*time.Hour, f.Expiry)
+	assert.Equal(t, "48h0m0s", fs.Lookup("expiry").Value.String())
+
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(std, &f, ExtendedDurations(), Prefix("x-")))
+	require.NoError(t, std.Parse([]string{"-x-expiry", "1w"}))
+	assert.Equal(t, 7*24*time.Hour, f.Expiry)
+
+	var g struct{ Retention time.Duration }
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &g))
+	assert.Error(t, fs.Set("retention", "1d"), "opt-in")
+}
@@ -20,7 +20,11 @@
 
 package flagbind
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // ErrorInvalidType is returned from Bind if v is not a pointer to a struct."
 type ErrorInvalidType struct {
@@ -40,7 +44,18 @@ func (err ErrorInvalidType) Error() string {
 // STDFlagSet or PFlagSet.
 var ErrorInvalidFlagSet = fmt.Errorf("flg must implement STDFlagSet or PFlagSet")
 
-func newErrorNestedStruct(fieldName string, err error) ErrorNestedStruct {
+func newErrorNestedStruct(fieldName string, err error) error {
+	// ErrorDuplicateFlag already identifies both full field paths.
+	if err, ok := err.(ErrorDuplicateFlag); ok {
+		return err
+	}
+	if err, ok := err.(ErrorExistingFlag); ok {
+		return err
+	}
+	if err, ok := err.(ErrorDefaultValue); ok {
+		err.FieldName = fmt.Sprintf("%v.%v", fieldName, err.FieldName)
+		return err
+	}
 	if err, ok := err.(ErrorNestedStruct); ok {
 		err.FieldName = fmt.Sprintf("%v.%v", fieldName, err.FieldName)
 		return err
@@ -66,7 +81,8 @@ func (err ErrorNestedStruct) Unwrap() error {
 }
 
 // ErrorDefaultValue is returned from Bind if the <default> value given in the
-// tag cannot be parsed and assigned to the field.
+// tag cannot be parsed and assigned to the field. FieldName is the dotted path
+// to the field, and it is not wrapped in ErrorNestedStruct.
 type ErrorDefaultValue struct {
 	FieldName string
 	Value     string
@@ -75,8 +91,12 @@ type ErrorDefaultValue struct {
 
 // Error implements error.
 func (err ErrorDefaultValue) Error() string {
-	return fmt.Sprintf("%v: cannot assign default value from tag: %q",
-		err.FieldName, err.Value)
+	if err.Err == nil {
+		return fmt.Sprintf("%v: cannot assign default value %q",
+			err.FieldName, err.Value)
+	}
+	return fmt.Sprintf("%v: cannot assign default value %q: %v",
+		err.FieldName, err.Value, err.Err)
 }
 
 // Unwrap implements Unwrap.
@@ -93,3 +113,236 @@ type ErrorFlagOverrideUndefined struct {
 func (err ErrorFlagOverrideUndefined) Error() string {
 	return fmt.Sprintf("cannot override undefined flag: %q", err.FlagName)
 }
+
+// ErrorFlagOverrideShortName is returned by Bind if a flag override tag sets a
+// ShortName on a FlagName that was already defined without it.
+type ErrorFlagOverrideShortName struct {
+	FlagName  string
+	ShortName string
+}
+
+func (err ErrorFlagOverrideShortName) Error() string {
+	return fmt.Sprintf("cannot override short name of flag %q with %q",
+		err.FlagName, err.ShortName)
+}
+
+// ErrorAliasedField is returned by Bind if a field refers to the same memory as
+// a field that was already bound, such as when the same struct pointer is
+// reachable through two different fields.
+type ErrorAliasedField struct {
+	FieldName string
+	AliasOf   string
+}
+
+func (err ErrorAliasedField) Error() string {
+	return fmt.Sprintf("%v: cannot bind memory already bound by field %v",
+		err.FieldName, err.AliasOf)
+}
+
+// ErrorFlagTag is returned by Bind if the flag Tag of the field FieldName is
+// malformed.
+type ErrorFlagTag struct {
+	FieldName string
+	Tag       string
+	Err       error
+}
+
+func (err ErrorFlagTag) Error() string {
+	return fmt.Sprintf("%v: invalid flag tag %q: %v",
+		err.FieldName, err.Tag, err.Err)
+}
+
+// Unwrap implements Unwrap.
+func (err ErrorFlagTag) Unwrap() error {
+	return err.Err
+}
+
+// ErrorMissingTag is returned by Bind if the RequireTags Option is used and
+// the field FieldName has no `flag` tag.
+type ErrorMissingTag struct {
+	FieldName string
+}
+
+func (err ErrorMissingTag) Error() string {
+	return fmt.Sprintf("%v: missing flag tag", err.FieldName)
+}
+
+// ErrorValueSource is returned by Bind if the ValueSource set by the
+// DefaultsFrom Option returns an error for Key.
+type ErrorValueSource struct {
+	Key string
+	Err error
+}
+
+func (err ErrorValueSource) Error() string {
+	return fmt.Sprintf("cannot look up %q: %v", err.Key, err.Err)
+}
+
+// Unwrap implements Unwrap.
+func (err ErrorValueSource) Unwrap() error {
+	return err.Err
+}
+
+// ErrorSecret is returned by Bind if the `secret=<scheme>:<ref>` tag option
+// Secret does not name a registered secret resolver, and by the Layer from
+// SecretLayer if the Secret cannot be resolved.
+type ErrorSecret struct {
+	Secret string
+	Err    error
+}
+
+func (err ErrorSecret) Error() string {
+	return fmt.Sprintf("cannot resolve secret %q: %v", err.Secret, err.Err)
+}
+
+// Unwrap implements Unwrap.
+func (err ErrorSecret) Unwrap() error {
+	return err.Err
+}
+
+// ErrorTagOption is returned by Bind if a flag tag <option> is invalid.
+type ErrorTagOption struct {
+	FlagName string
+	Option   string
+}
+
+func (err ErrorTagOption) Error() string {
+	return fmt.Sprintf("flag %q: invalid tag option: %q",
+		err.FlagName, err.Option)
+}
+
+// ErrorFlagRequires is returned by Validate if FlagName is set but a flag it
+// requires is not.
+type ErrorFlagRequires struct {
+	FlagName string
+	Requires string
+}
+
+func (err ErrorFlagRequires) Error() string {
+	return fmt.Sprintf("flag %q requires flag %q to be set",
+		err.FlagName, err.Requires)
+}
+
+// ErrorFlagConflicts is returned by Validate if FlagName and a flag that it
+// conflicts with are both set.
+type ErrorFlagConflicts struct {
+	FlagName  string
+	Conflicts string
+}
+
+func (err ErrorFlagConflicts) Error() string {
+	return fmt.Sprintf("flags %q and %q cannot be used together",
+		err.FlagName, err.Conflicts)
+}
+
+// ErrorFlagOneOf is returned by Validate if none of the Flags in a `oneof`
+// Group are set.
+type ErrorFlagOneOf struct {
+	Group string
+	Flags []string
+}
+
+func (err ErrorFlagOneOf) Error() string {
+	quoted := make([]string, len(err.Flags))
+	for i, name := range err.Flags {
+		quoted[i] = strconv.Quote(name)
+	}
+	return fmt.Sprintf("one of flags %v must be set",
+		strings.Join(quoted, ", "))
+}
+
+// ErrorDuplicateFlag is returned by Bind if the field at the dotted path
+// FieldName maps to the same FlagName as the field at DefinedBy, such as due
+// to prefixes or flattening. It is not wrapped in ErrorNestedStruct.
+type ErrorDuplicateFlag struct {
+	FlagName  string
+	FieldName string
+	DefinedBy string
+}
+
+func (err ErrorDuplicateFlag) Error() string {
+	return fmt.Sprintf("%v and %v both map to %q",
+		err.DefinedBy, err.FieldName, err.FlagName)
+}
+
+// ErrorExistingFlag is returned by Bind with SkipExisting if the field at the
+// dotted path FieldName cannot share the flag FlagName that is already
+// defined, such as because it would be bound as a different type. It is not
+// wrapped in ErrorNestedStruct.
+type ErrorExistingFlag struct {
+	FlagName  string
+	FieldName string
+	Err       error
+}
+
+func (err ErrorExistingFlag) Error() string {
+	return fmt.Sprintf("%v: cannot share existing flag %q: %v",
+		err.FieldName, err.FlagName, err.Err)
+}
+
+// Unwrap implements Unwrap.
+func (err ErrorExistingFlag) Unwrap() error {
+	return err.Err
+}
+
+// ErrorShortName is returned by Bind if the StrictShortNames Option is used
+// and a short name would be ignored.
+type ErrorShortName struct {
+	FieldName string
+	ShortName string
+}
+
+func (err ErrorShortName) Error() string {
+	if len(err.ShortName) > 1 {
+		return fmt.Sprintf("%v: short name %q must be a single character",
+			err.FieldName, err.ShortName)
+	}
+	return fmt.Sprintf("%v: short name %q requires a PFlagSet",
+		err.FieldName, err.ShortName)
+}
+
+// ErrorResolve is returned by Resolve if the flag FlagName cannot be looked up
+// in, or set from, a Layer with Origin.
+type ErrorResolve struct {
+	FlagName string
+	Origin   Origin
+	Err      error
+}
+
+func (err ErrorResolve) Error() string {
+	return fmt.Sprintf("flag %q from %v: %v", err.FlagName, err.Origin, err.Err)
+}
+
+// Unwrap implements Unwrap.
+func (err ErrorResolve) Unwrap() error {
+	return err.Err
+}
+
+// ErrorParse is returned by Commands.Run if the flags of Command cannot be
+// parsed, such as for an undefined flag or an invalid value.
+type ErrorParse struct {
+	Command string
+	Err     error
+}
+
+func (err ErrorParse) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap implements Unwrap.
+func (err ErrorParse) Unwrap() error {
+	return err.Err
+}
+
+// ErrorMissingCommand is returned by Commands.Run if no command is given.
+var ErrorMissingCommand = fmt.Errorf("missing command")
+
+// ErrorUnknownCommand is returned by Commands.Run if the Command is not
+// defined.
+type ErrorUnknownCommand struct {
+	Command string
+}
+
+func (err ErrorUnknownCommand) Error() string {
+	return fmt.Sprintf("unknown command: %q", err.Command)
+}
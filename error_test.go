@@ -31,6 +31,13 @@ import (
 func TestErrorDefaultValueUnwrap(t *testing.T) {
 	err := ErrorDefaultValue{"", "", strconv.ErrSyntax}
 	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+
+	nested := newErrorNestedStruct("A", newErrorNestedStruct("B",
+		ErrorDefaultValue{"C", "x", strconv.ErrSyntax}))
+	assert.Equal(t, ErrorDefaultValue{"A.B.C", "x", strconv.ErrSyntax},
+		nested)
+	assert.EqualError(t, nested,
+		`A.B.C: cannot assign default value "x": invalid syntax`)
 }
 func TestErrorNestedStructUnwrap(t *testing.T) {
 	err := newErrorNestedStruct("C", strconv.ErrSyntax)
@@ -0,0 +1,135 @@
+package flagbind
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// transformValue is a flag.Value that transforms the text passed to Set before
+// setting the underlying Value. It preserves the Type of the underlying Value
+// so that it may wrap both flag and pflag Values. See boolFlagValue for
+// IsBoolFlag.
+type transformValue struct {
+	flag.Value
+	transform func(string) (string, error)
+}
+
+func (v transformValue) Set(text string) error {
+	text, err := v.transform(text)
+	if err != nil {
+		return err
+	}
+	return v.Value.Set(text)
+}
+
+// String is safe to call on the zero transformValue, as flag.PrintDefaults
+// does.
+func (v transformValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+func (v transformValue) Type() string {
+	if v, ok := v.Value.(interface{ Type() string }); ok {
+		return v.Type()
+	}
+	return ""
+}
+
+// boolFlagValue is a wrapper of the Value of a boolean flag, which may be set
+// without a value. Wrappers only implement IsBoolFlag through boolFlagValue,
+// since both flag packages treat any Value with IsBoolFlag specially.
+type boolFlagValue struct{ pflag.Value }
+
+func (v boolFlagValue) IsBoolFlag() bool { return true }
+
+// String returns "false" for the zero boolFlagValue, so that
+// flag.PrintDefaults does not display a false default.
+func (v boolFlagValue) String() string {
+	if v.Value == nil {
+		return "false"
+	}
+	return v.Value.String()
+}
+
+// keepBoolFlag returns the wrapper w of the Value v, as a boolFlagValue if v
+// is the Value of a boolean flag.
+func keepBoolFlag(w pflag.Value, v flag.Value) pflag.Value {
+	if isBoolFlag(v) {
+		return boolFlagValue{w}
+	}
+	return w
+}
+
+// transformFlag wraps the Value of the flag name so that transform is applied
+// to any text before it is Set.
+func transformFlag(fs FlagSet, name string,
+	transform func(string) (string, error)) {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(transformValue{f.Value, transform}, f.Value)
+	case PFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(transformValue{f.Value, transform}, f.Value)
+	}
+}
+
+// checkChoices returns a transform that returns an error if the text is not
+// one of the choices.
+func checkChoices(choices []string) func(string) (string, error) {
+	return func(text string) (string, error) {
+		if !containsString(choices, text) {
+			return "", fmt.Errorf("%q is not one of: %v",
+				text, strings.Join(choices, ", "))
+		}
+		return text, nil
+	}
+}
+
+// expandFile replaces text of the form `@<path>` with the contents of the file
+// at <path>, with a single trailing newline removed. A leading `@@` escapes a
+// literal `@`.
+func expandFile(text string) (string, error) {
+	if !strings.HasPrefix(text, "@") {
+		return text, nil
+	}
+	if strings.HasPrefix(text, "@@") {
+		return text[1:], nil
+	}
+	data, err := ioutil.ReadFile(text[1:])
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(text, "\r"), nil
+}
+
+// expandDefault expands environment variables in the tag default defValue,
+// and a leading "~" followed by a slash, or alone, to the home directory.
+func expandDefault(defValue string) string {
+	defValue = os.ExpandEnv(defValue)
+	if defValue != "~" && !strings.HasPrefix(defValue, "~/") &&
+		!strings.HasPrefix(defValue, "~"+string(filepath.Separator)) {
+		return defValue
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defValue
+	}
+	return home + defValue[1:]
+}
+
+// expandEnv replaces ${var} or $var in text according to the values of the
+// current environment variables.
+func expandEnv(text string) (string, error) {
+	return os.ExpandEnv(text), nil
+}
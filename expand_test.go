@@ -0,0 +1,121 @@
+package flagbind
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flagbind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	token := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(token, []byte("secret\n"), 0600))
+	body := filepath.Join(dir, "body.json")
+	require.NoError(t, ioutil.WriteFile(body, []byte(`{"a":1}`), 0600))
+
+	type Flags struct {
+		Token   string          `flag:";;;expand-file"`
+		Body    json.RawMessage `flag:";;;expand-file"`
+		At      string          `flag:";@@home;;expand-file"`
+		Verbose bool            `flag:";;;expand-file"`
+		Literal string
+	}
+	args := []string{
+		"--token", "@" + token,
+		"--body", "@" + body,
+		"--verbose",
+		"--literal", "@" + token,
+	}
+	check := func(t *testing.T, f Flags) {
+		assert := assert.New(t)
+		assert.Equal("secret", f.Token)
+		assert.Equal(`{"a":1}`, string(f.Body))
+		assert.Equal("@home", f.At)
+		assert.True(f.Verbose)
+		assert.Equal("@"+token, f.Literal)
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		var f Flags
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		require.NoError(t, Bind(fs, &f))
+		require.NoError(t, fs.Parse(args))
+		check(t, f)
+
+		fs.SetOutput(ioutil.Discard)
+		err := fs.Parse([]string{"--token", "@" + filepath.Join(dir, "x")})
+		assert.Error(t, err)
+	})
+	t.Run("pflag", func(t *testing.T) {
+		var f Flags
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		require.NoError(t, Bind(fs, &f))
+		require.NoError(t, fs.Parse(args))
+		check(t, f)
+		assert.Equal(t, "JSON", fs.Lookup("body").Value.Type())
+	})
+}
+
+func TestExpandEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flagbind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"),
+		[]byte("secret"), 0600))
+
+	defer os.Setenv("FLAGBIND_TEST_DIR", os.Getenv("FLAGBIND_TEST_DIR"))
+	require.NoError(t, os.Setenv("FLAGBIND_TEST_DIR", dir))
+
+	var f struct {
+		Config string `flag:";$FLAGBIND_TEST_DIR/config;;expand-env"`
+		Token  string `flag:";;;expand-env,expand-file"`
+		Raw    string
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, dir+"/config", f.Config)
+	assert.Equal(t, "$FLAGBIND_TEST_DIR/config", fs.Lookup("config").DefValue)
+
+	require.NoError(t, fs.Parse([]string{
+		"--token", "@${FLAGBIND_TEST_DIR}/token",
+		"--raw", "$FLAGBIND_TEST_DIR",
+	}))
+	assert.Equal(t, "secret", f.Token)
+	assert.Equal(t, "$FLAGBIND_TEST_DIR", f.Raw)
+}
+
+func TestExpandDefaults(t *testing.T) {
+	defer os.Setenv("FLAGBIND_TEST_CACHE", os.Getenv("FLAGBIND_TEST_CACHE"))
+	require.NoError(t, os.Setenv("FLAGBIND_TEST_CACHE", "/cache"))
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	type Flags struct {
+		Cache string `flag:";$FLAGBIND_TEST_CACHE/app"`
+		Data  string `flag:";~/data"`
+		Tilde string `flag:";a~b"`
+	}
+
+	var f Flags
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, ExpandDefaults()))
+	assert.Equal(t, "/cache/app", f.Cache)
+	assert.Equal(t, filepath.Join(home, "data"), f.Data)
+	assert.Equal(t, "a~b", f.Tilde)
+	assert.Equal(t, "/cache/app", fs.Lookup("cache").DefValue)
+
+	f = Flags{}
+	require.NoError(t, Bind(flag.NewFlagSet("", flag.ContinueOnError), &f))
+	assert.Equal(t, "$FLAGBIND_TEST_CACHE/app", f.Cache)
+	assert.Equal(t, "~/data", f.Data)
+}
@@ -0,0 +1,28 @@
+package flagbind
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FileMode is an os.FileMode flag.Value that parses and prints octal, such as
+// 0644. A leading "0o" is also accepted.
+type FileMode os.FileMode
+
+func (m *FileMode) Set(text string) error {
+	octal := strings.TrimPrefix(strings.ToLower(text), "0o")
+	mode, err := strconv.ParseUint(octal, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid octal file mode %q", text)
+	}
+	*m = FileMode(mode)
+	return nil
+}
+
+func (m FileMode) String() string {
+	return fmt.Sprintf("%#o", uint32(m))
+}
+
+func (m FileMode) Type() string { return "FileMode" }
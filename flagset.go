@@ -73,20 +73,33 @@ type PFlagSet interface {
 	Float64VarP(p *float64, name, short string, value float64, usage string)
 	Float64SliceVarP(p *[]float64, name, short string, value []float64, usage string)
 
+	Int8VarP(p *int8, name, short string, value int8, usage string)
+	Int16VarP(p *int16, name, short string, value int16, usage string)
+	Int32VarP(p *int32, name, short string, value int32, usage string)
+
 	Int64VarP(p *int64, name, short string, value int64, usage string)
 	Int64SliceVarP(p *[]int64, name, short string, value []int64, usage string)
 
 	IntVarP(p *int, name, short string, value int, usage string)
+	CountVarP(p *int, name, short string, usage string)
 	IntSliceVarP(p *[]int, name, short string, value []int, usage string)
 
 	StringVarP(p *string, name, short string, value string, usage string)
 	StringSliceVarP(p *[]string, name, short string, value []string, usage string)
+	StringArrayVarP(p *[]string, name, short string, value []string, usage string)
+	StringToStringVarP(p *map[string]string, name, short string, value map[string]string, usage string)
 
+	Uint8VarP(p *uint8, name, short string, value uint8, usage string)
+	Uint16VarP(p *uint16, name, short string, value uint16, usage string)
+	Uint32VarP(p *uint32, name, short string, value uint32, usage string)
 	Uint64VarP(p *uint64, name, short string, value uint64, usage string)
 
 	UintVarP(p *uint, name, short string, value uint, usage string)
 	UintSliceVarP(p *[]uint, name, short string, value []uint, usage string)
 
+	BytesHexVarP(p *[]byte, name, shorthand string, value []byte, usage string)
+	BytesBase64VarP(p *[]byte, name, shorthand string, value []byte, usage string)
+
 	IPVarP(p *net.IP, name, shorthand string, value net.IP, usage string)
 	IPSliceVarP(p *[]net.IP, name, shorthand string, value []net.IP, usage string)
 
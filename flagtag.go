@@ -21,6 +21,8 @@
 package flagbind
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -32,10 +34,16 @@ type flagTag struct {
 	HasExplicitName bool
 	IsIgnored       bool
 
+	// InvalidShortName is a short name that was too long and was dropped.
+	InvalidShortName string
+
 	// `flag:";<default value>"`
 	// Number int `flag:";5"`
 	DefValue string
 
+	// DefaultFunc names a registered func that computes the default.
+	DefaultFunc string // `flag:";;;default-func=num-cpu"`
+
 	// `flag:";;<usage>"`
 	// Number int `flag:";;Number of times to do"`
 	// _ struct{} `use:"something"`
@@ -44,26 +52,129 @@ type flagTag struct {
 	// Options
 	// `flag:";;;<options>"`
 
+	// Options lists each known option as it appeared in the tag.
+	Options []string
+
+	// UnknownOptions lists each option that is not known, such as a typo.
+	UnknownOptions []string
+
 	// Number int `flag:";;;hide-default,hidden"`
-	HideDefault bool // `flag:";;;hide-default"`
-	Hidden      bool // `flag:";;;hidden"`
+	HideDefault     bool // `flag:";;;hide-default"`
+	Hidden          bool // `flag:";;;hidden"`
+	SkipZeroDefault bool // `flag:";;;skip-zero-default"`
+
+	// Key is looked up in the ValueSource instead of the flag name.
+	Key string // `flag:";;;key=app/timeout"`
+
+	// OnChange names a registered func called when the flag is Set.
+	OnChange string // `flag:";;;onchange=set-log-level"`
+
+	// Aliases are additional flag names for the field.
+	Aliases []string // `flag:";;;aliases=old-name|legacy-name"`
+
+	// RenamedFrom are former flag names for the field, which are
+	// deprecated.
+	RenamedFrom []string // `flag:";;;renamed-from=old-name"`
+
+	// Deprecated is the deprecation message.
+	Deprecated string // `flag:";;;deprecated=use --other"`
+
+	// Requires lists flags that must also be set if this flag is set.
+	Requires []string // `flag:";;;requires=tls-cert|tls-key"`
+
+	// Conflicts lists flags that may not be set if this flag is set.
+	Conflicts []string // `flag:";;;conflicts=quiet"`
+
+	// Exactly one, or at most one, of the flags in the group may be set.
+	OneOf     string // `flag:";;;oneof=input"`
+	AtMostOne string // `flag:";;;at-most-one=input"`
 
 	// Nested struct
-	Flatten bool // `flag:";;;flatten"`
+	Flatten bool   // `flag:";;;flatten"`
+	Sep     string // `flag:";;;sep=."`
+	HasSep  bool
+
+	// int
+	Count bool // `flag:";;;count"`
+
+	// JSON
+	JSON bool // `flag:";;;json"` or `flag:";;;inline-json"`
+
+	// Maps and JSON
+	Merge bool // `flag:";;;merge"`
+
+	// []string
+	Array bool // `flag:";;;array"`
+
+	// []byte
+	Encoding string // `flag:";;;encoding=hex"`
+
+	// time.Time and []time.Time
+	Layout string // `flag:";;;layout=RFC3339"`
+
+	// url.URL
+	Schemes     []string // `flag:";;;schemes=https|wss"`
+	RequireHost bool     // `flag:";;;require-host"`
+
+	// The value must be one of the choices.
+	Choices []string // `flag:";;;choices=json|yaml|text"`
+
+	// Maps and slices of structs
+	Keys []string // `flag:";;;keys=primary|backup"`
+	Len  int      // `flag:";;;len=3"`
+
+	// Normalize the value with the registered normalizers.
+	Normalize []string // `flag:";;;normalize=trim|lower"`
+
+	// Read values of the form `@<path>` from a file.
+	ExpandFile bool // `flag:";;;expand-file"`
+
+	// Expand environment variables in values.
+	ExpandEnv bool // `flag:";;;expand-env"`
+
+	// Redact the value wherever it is displayed.
+	Sensitive bool // `flag:";;;sensitive"`
+
+	// The Origins that Resolve may set the value from, in order of
+	// precedence.
+	Precedence []Origin // `flag:";;;precedence=env|flag"`
+
+	// time.Duration, set by the ExtendedDurations Option.
+	ExtendedDuration bool
+
+	// File and Dir, set by Inspect, which skips their checks.
+	Unchecked bool
+
+	// string
+	Secret    bool   // `flag:";;;secret"`
+	SecretRef string // `flag:";;;secret=aws-ssm:/app/db-password"`
+
+	// File
+	Exists    bool // `flag:";;;exists"`
+	NotExists bool // `flag:";;;not-exists"`
+	Readable  bool // `flag:";;;readable"`
+	Create    bool // `flag:";;;create"`
 }
 
-// newFlagTag parses all possible tag settings.
-func newFlagTag(tag string) (fTag flagTag) {
+// newFlagTag parses all possible tag settings. It returns an error if the tag
+// is malformed, such as when it has more than four settings, more than two
+// names, or an empty option.
+func newFlagTag(tag string) (fTag flagTag, err error) {
 	if tag == "" {
 		return
 	}
 	args := strings.Split(tag, ";")
+	if len(args) > 4 {
+		return fTag, fmt.Errorf("too many settings: %v", len(args))
+	}
 	fTag.IsIgnored = args[0] == "-"
 	if fTag.IsIgnored {
 		return
 	}
 
-	fTag.parseNames(args[0])
+	if err := fTag.parseNames(args[0]); err != nil {
+		return fTag, err
+	}
 	if len(args) == 1 {
 		return
 	}
@@ -78,14 +189,16 @@ func newFlagTag(tag string) (fTag flagTag) {
 		return
 	}
 
-	fTag.parseOptions(args[3])
-	return
+	return fTag, fTag.parseOptions(args[3])
 }
 
 // parseNames parses and sorts the long and short flag names.
-func (fTag *flagTag) parseNames(name string) {
+func (fTag *flagTag) parseNames(name string) error {
 
 	names := strings.Split(name, ",")
+	if len(names) > 2 {
+		return fmt.Errorf("too many names: %q", name)
+	}
 
 	fTag.Name = strings.TrimLeft(names[0], "-")
 	if len(names) > 1 {
@@ -99,6 +212,7 @@ func (fTag *flagTag) parseNames(name string) {
 
 	// If short name is too long, censor it.
 	if len(fTag.ShortName) > 1 {
+		fTag.InvalidShortName = fTag.ShortName
 		fTag.ShortName = ""
 	}
 
@@ -108,12 +222,166 @@ func (fTag *flagTag) parseNames(name string) {
 	}
 
 	fTag.HasExplicitName = fTag.Name != ""
+	return nil
+}
+
+// parseOptions parses the comma separated options. Options that take a value
+// use the form `<option>=<value>`. A value that is a list separates its
+// elements with "|", such as `schemes=https|wss`, so that a list never
+// absorbs the options that follow it. Empty options, such as in
+// `hidden,,flatten`, are an error.
+func (fTag *flagTag) parseOptions(opts string) error {
+	if strings.TrimSpace(opts) == "" {
+		return nil
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if strings.TrimSpace(opt) == "" {
+			return fmt.Errorf("empty option: %q", opts)
+		}
+		fTag.addOption(opt)
+	}
+	return nil
 }
 
-// parseOptions parses the hidden, hide-default, and flatten options.
-func (fTag *flagTag) parseOptions(opts string) {
-	opts = strings.ToLower(opts)
-	fTag.Hidden = strings.Contains(opts, "hidden")
-	fTag.HideDefault = strings.Contains(opts, "hide-default")
-	fTag.Flatten = strings.Contains(opts, "flatten")
+// addOption sets opt and records it in Options, if it is known, or else in
+// UnknownOptions.
+func (fTag *flagTag) addOption(opt string) {
+	opt = strings.TrimSpace(opt)
+	switch {
+	case fTag.setOption(opt):
+		fTag.Options = append(fTag.Options, opt)
+	default:
+		fTag.UnknownOptions = append(fTag.UnknownOptions, opt)
+	}
+}
+
+// setOption sets a single `<option>[=<value>]` and reports whether the option
+// is known and its value is valid.
+func (fTag *flagTag) setOption(opt string) bool {
+	var val string
+	if i := strings.Index(opt, "="); i >= 0 {
+		opt, val = opt[:i], strings.TrimSpace(opt[i+1:])
+	}
+	opt = strings.ToLower(strings.TrimSpace(opt))
+	if listOptions[opt] && containsString(splitList(val), "") {
+		return false
+	}
+	switch opt {
+	case "hidden":
+		fTag.Hidden = true
+	case "hide-default":
+		fTag.HideDefault = true
+	case "default-func":
+		fTag.DefaultFunc = val
+	case "key":
+		fTag.Key = val
+	case "onchange":
+		fTag.OnChange = val
+	case "aliases":
+		fTag.Aliases = splitList(val)
+	case "renamed-from":
+		fTag.RenamedFrom = splitList(val)
+	case "deprecated":
+		fTag.Deprecated = val
+	case "requires":
+		fTag.Requires = splitList(val)
+	case "conflicts":
+		fTag.Conflicts = splitList(val)
+	case "oneof":
+		fTag.OneOf = val
+	case "at-most-one":
+		fTag.AtMostOne = val
+	case "skip-zero-default":
+		fTag.SkipZeroDefault = true
+	case "flatten":
+		fTag.Flatten = true
+	case "sep":
+		fTag.Sep = val
+		fTag.HasSep = true
+	case "json", "inline-json":
+		fTag.JSON = true
+	case "count":
+		fTag.Count = true
+	case "merge":
+		fTag.Merge = true
+	case "array":
+		fTag.Array = true
+	case "encoding":
+		fTag.Encoding = strings.ToLower(val)
+	case "layout":
+		fTag.Layout = val
+	case "schemes":
+		fTag.Schemes = splitList(strings.ToLower(val))
+	case "require-host":
+		fTag.RequireHost = true
+	case "choices":
+		fTag.Choices = splitList(val)
+	case "keys":
+		fTag.Keys = splitList(val)
+	case "len":
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return false
+		}
+		fTag.Len = n
+	case "normalize":
+		fTag.Normalize = splitList(val)
+	case "expand-file":
+		fTag.ExpandFile = true
+	case "expand-env":
+		fTag.ExpandEnv = true
+	case "sensitive":
+		fTag.Sensitive = true
+	case "precedence":
+		fTag.Precedence = nil
+		for _, name := range splitList(val) {
+			origin, ok := parseOrigin(name)
+			if !ok {
+				return false
+			}
+			fTag.Precedence = append(fTag.Precedence, origin)
+		}
+		if len(fTag.Precedence) == 0 {
+			return false
+		}
+	case "secret":
+		fTag.Secret = true
+		fTag.SecretRef = val
+	case "exists":
+		fTag.Exists = true
+	case "not-exists":
+		fTag.NotExists = true
+	case "readable":
+		fTag.Readable = true
+	case "create":
+		fTag.Create = true
+	default:
+		return false
+	}
+	return true
+}
+
+// listOptions are the options whose value is a list.
+var listOptions = map[string]bool{
+	"aliases":      true,
+	"renamed-from": true,
+	"requires":     true,
+	"conflicts":    true,
+	"schemes":      true,
+	"choices":      true,
+	"keys":         true,
+	"normalize":    true,
+	"precedence":   true,
+}
+
+// splitList splits a "|" separated list and trims space from each element.
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	elems := strings.Split(list, "|")
+	for i := range elems {
+		elems[i] = strings.TrimSpace(elems[i])
+	}
+	return elems
 }
@@ -0,0 +1,45 @@
+//go:build go1.18
+// +build go1.18
+
+package flagbind
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzNewFlagTag(f *testing.F) {
+	for _, tag := range []string{
+		"",
+		"-",
+		"name,n;def;Usage;hidden,choices=a|b",
+		",v;;;flatten",
+		";;;schemes=https|wss,require-host",
+		"db.;;;sep=.",
+	} {
+		f.Add(tag)
+	}
+	f.Fuzz(func(t *testing.T, tag string) {
+		fTag, err := newFlagTag(tag)
+		if err != nil || fTag.IsIgnored {
+			return
+		}
+		if len(fTag.ShortName) > 1 {
+			t.Errorf("%q: short name %q is too long", tag, fTag.ShortName)
+		}
+		if strings.HasPrefix(fTag.Name, "-") ||
+			strings.HasPrefix(fTag.ShortName, "-") {
+			t.Errorf("%q: leading dash in %q,%q",
+				tag, fTag.Name, fTag.ShortName)
+		}
+		if fTag.HasExplicitName != (fTag.Name != "") {
+			t.Errorf("%q: HasExplicitName is %v for name %q",
+				tag, fTag.HasExplicitName, fTag.Name)
+		}
+		for _, opt := range append(fTag.Options, fTag.UnknownOptions...) {
+			if strings.TrimSpace(opt) == "" {
+				t.Errorf("%q: empty option", tag)
+			}
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package flagbind
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFlagTag(t *testing.T) {
+	tag, err := newFlagTag("name,n;def;Usage;hidden,choices=a|b")
+	require.NoError(t, err)
+	assert.Equal(t, "name", tag.Name)
+	assert.Equal(t, "n", tag.ShortName)
+	assert.Equal(t, "def", tag.DefValue)
+	assert.Equal(t, "Usage", tag.Usage)
+	assert.Equal(t, []string{"hidden", "choices=a|b"}, tag.Options)
+
+	// Origins that are also option names are listed in the precedence.
+	tag, err = newFlagTag(";;;precedence=env|secret|flag,hidden")
+	require.NoError(t, err)
+	assert.Equal(t, []Origin{OriginEnv, OriginSecret, OriginFlag},
+		tag.Precedence)
+	assert.True(t, tag.Hidden)
+	assert.False(t, tag.Secret)
+
+	// Lists never absorb the options that follow them.
+	tag, err = newFlagTag(";;;choices=low|hidden,aliases=old|count,hiden")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"low", "hidden"}, tag.Choices)
+	assert.Equal(t, []string{"old", "count"}, tag.Aliases)
+	assert.False(t, tag.Hidden)
+	assert.False(t, tag.Count)
+	assert.Equal(t, []string{"hiden"}, tag.UnknownOptions)
+
+	for _, test := range []struct {
+		Tag string
+		Err string
+	}{
+		{"name;;;;", "too many settings: 5"},
+		{"a,b,c", `too many names: "a,b,c"`},
+		{";;;hidden,,flatten", `empty option: "hidden,,flatten"`},
+		{";;;hidden,", `empty option: "hidden,"`},
+		{";;;choices=a|b, ,hidden", `empty option: "choices=a|b, ,hidden"`},
+	} {
+		_, err := newFlagTag(test.Tag)
+		assert.EqualError(t, err, test.Err, test.Tag)
+	}
+
+	var f struct {
+		Value int `flag:"a,b,c"`
+	}
+	assert.EqualError(t, Bind(pflag.NewFlagSet("", pflag.ContinueOnError), &f),
+		`Value: invalid flag tag "a,b,c": too many names: "a,b,c"`)
+}
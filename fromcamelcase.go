@@ -21,64 +21,86 @@
 package flagbind
 
 import (
+	"strings"
 	"unicode"
 )
 
-// FromCamelCase converts CamelCase to kebab-case, or snake_case, or lowercase,
-// depending on `sep`.
-//
-// It makes a best effort at respecting capitalized acronyms. For example:
-//
-//      camel -> camel
-//      CamelCamel -> camel-camel
-//      CamelID -> camel-id
-//      IDCamel -> id-camel
-//      APICamel -> api-camel
-//      APIURL -> apiurl
-//      ApiUrl -> api-url
-//      APIUrlID -> api-url-id
-func FromCamelCase(name, sep string) string {
+// Splitter splits a Go identifier into the words used to derive a flag name.
+// The words are joined using Separator.
+type Splitter interface {
+	Split(name string) []string
+}
+
+// SplitterFunc adapts a func to the Splitter interface.
+type SplitterFunc func(name string) []string
 
-	var kebab string
-	var acronym string
+// Split calls fn(name).
+func (fn SplitterFunc) Split(name string) []string {
+	return fn(name)
+}
+
+// CamelCaseSplitter is the default Splitter. It splits CamelCase into lower case
+// words, making a best effort at respecting capitalized acronyms. See
+// FromCamelCase.
+type CamelCaseSplitter struct{}
+
+// Split implements Splitter.
+func (CamelCaseSplitter) Split(name string) []string {
+
+	var words []string
+	var word strings.Builder
+	var acronym []rune
 	for _, r := range name {
 
 		if unicode.IsUpper(r) {
-			acronym += string(unicode.ToLower(r))
+			acronym = append(acronym, unicode.ToLower(r))
 			continue
 		}
 
-		if len(acronym) > 1 {
+		if len(acronym) > 0 {
 
-			if kebab != "" {
-				kebab += sep
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
 			}
 
-			// omit last char
-			kebab += acronym[:len(acronym)-1]
-
-			// add last char after separator
-			kebab += sep + acronym[len(acronym)-1:]
-
-			acronym = ""
-
-		} else if acronym != "" {
-
-			if kebab != "" {
-				kebab += sep
+			if len(acronym) > 1 {
+				// The last upper case letter begins the next
+				// word.
+				words = append(words, string(acronym[:len(acronym)-1]))
+				acronym = acronym[len(acronym)-1:]
 			}
 
-			kebab += acronym
-			acronym = ""
-
+			word.WriteRune(acronym[0])
+			acronym = acronym[:0]
 		}
 
-		kebab += string(r)
+		word.WriteRune(r)
 	}
 
-	if kebab != "" && acronym != "" {
-		kebab += sep
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	if len(acronym) > 0 {
+		words = append(words, string(acronym))
 	}
 
-	return kebab + acronym
+	return words
+}
+
+// FromCamelCase converts CamelCase to kebab-case, or snake_case, or lowercase,
+// depending on `sep`.
+//
+// It makes a best effort at respecting capitalized acronyms. For example:
+//
+//      camel -> camel
+//      CamelCamel -> camel-camel
+//      CamelID -> camel-id
+//      IDCamel -> id-camel
+//      APICamel -> api-camel
+//      APIURL -> apiurl
+//      ApiUrl -> api-url
+//      APIUrlID -> api-url-id
+func FromCamelCase(name, sep string) string {
+	return strings.Join(CamelCaseSplitter{}.Split(name), sep)
 }
@@ -0,0 +1,35 @@
+package flagbind
+
+import "strconv"
+
+// funcValue is a flag.Value that calls the func that fn points to with the
+// text each time it is Set, like flag.Func.
+type funcValue struct {
+	fn *func(string) error
+}
+
+func (val funcValue) Set(text string) error { return (*val.fn)(text) }
+
+func (val funcValue) String() string { return "" }
+
+func (val funcValue) Type() string { return "string" }
+
+// boolFuncValue is a bool flag.Value that calls the func that fn points to each
+// time it is Set to true, such as by -trigger or -trigger=true.
+type boolFuncValue struct {
+	fn *func() error
+}
+
+func (val boolFuncValue) Set(text string) error {
+	b, err := strconv.ParseBool(text)
+	if err != nil || !b {
+		return err
+	}
+	return (*val.fn)()
+}
+
+func (val boolFuncValue) String() string { return "false" }
+
+func (val boolFuncValue) IsBoolFlag() bool { return true }
+
+func (val boolFuncValue) Type() string { return "bool" }
@@ -0,0 +1,176 @@
+package flagbind
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// isStructMap reports whether t is a map type with string keys and struct or
+// struct pointer values.
+func isStructMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String &&
+		isGroupElem(t.Elem())
+}
+
+// isStructSlice reports whether t is a slice type with struct or struct
+// pointer elements.
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && isGroupElem(t.Elem())
+}
+
+// isGroupElem reports whether t is a struct, or a pointer to a struct, that
+// Bind would dive into.
+func isGroupElem(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	p := reflect.New(t).Interface()
+	if _, ok := registeredValue(p); ok {
+		return false
+	}
+	return !isValue(p)
+}
+
+// mapKeys returns the keys to bind for the map m at path. Keys set by the
+// MapKeys Option take precedence over the `keys` tag option. Otherwise the
+// existing keys of m are used in sorted order.
+func (b bind) mapKeys(m reflect.Value, tag flagTag, path string) []string {
+	if keys, ok := b.MapKeys[path]; ok {
+		return keys
+	}
+	if len(tag.Keys) > 0 {
+		return tag.Keys
+	}
+	keys := make([]string, 0, m.Len())
+	for _, key := range m.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bindStructMap binds the struct for each key of the map m, which is the field
+// with the given name, using the prefix `<name>-<key>-`. Map values that are
+// not pointers are stored back into m whenever one of their flags is set.
+func (b bind) bindStructMap(fs FlagSet, m reflect.Value, tag flagTag,
+	name, path string) error {
+	keys := b.mapKeys(m, tag, path)
+	if len(keys) == 0 {
+		return nil
+	}
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	elemT := m.Type().Elem()
+	for _, key := range keys {
+		k := reflect.ValueOf(key).Convert(m.Type().Key())
+		elem := m.MapIndex(k)
+
+		var ptr reflect.Value
+		if elemT.Kind() == reflect.Ptr {
+			ptr = elem
+			if !ptr.IsValid() || ptr.IsNil() {
+				ptr = reflect.New(elemT.Elem())
+				m.SetMapIndex(k, ptr)
+			}
+		} else {
+			ptr = reflect.New(elemT)
+			if elem.IsValid() {
+				ptr.Elem().Set(elem)
+			}
+		}
+
+		b := b
+		b.Prefix = b.nestedPrefix(b.Prefix, tag, tag.Name, key)
+		b.Path = path + "[" + key + "]"
+		order := len(b.State.Order)
+		if err := b.bind(fs, ptr.Interface()); err != nil {
+			return newErrorNestedStruct(name+"["+key+"]", err)
+		}
+		b.State.recordFlags(fs, b.Path)
+
+		if elemT.Kind() == reflect.Ptr {
+			continue
+		}
+		store := func() error {
+			if b.Values != nil {
+				b.Values.mu.Lock()
+				defer b.Values.mu.Unlock()
+			}
+			m.SetMapIndex(k, ptr.Elem())
+			return nil
+		}
+		store()
+		for _, name := range b.State.Order[order:] {
+			afterSet(fs, name, store)
+		}
+	}
+	return nil
+}
+
+// bindStructSlice binds the struct for each index of the slice s, which is
+// the field with the given name, using the prefix `<name>-<index>-`. The slice
+// is grown to the `len` tag option, if it is shorter.
+func (b bind) bindStructSlice(fs FlagSet, s reflect.Value, tag flagTag,
+	name, path string) error {
+	if s.Len() < tag.Len {
+		grown := reflect.MakeSlice(s.Type(), tag.Len, tag.Len)
+		reflect.Copy(grown, s)
+		s.Set(grown)
+	}
+
+	for i := 0; i < s.Len(); i++ {
+		ptr := s.Index(i).Addr()
+		if elem := s.Index(i); elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			ptr = elem
+		}
+
+		index := strconv.Itoa(i)
+		b := b
+		b.Prefix = b.nestedPrefix(b.Prefix, tag, tag.Name, index)
+		b.Path = path + "[" + index + "]"
+		if err := b.bind(fs, ptr.Interface()); err != nil {
+			return newErrorNestedStruct(name+"["+index+"]", err)
+		}
+		b.State.recordFlags(fs, b.Path)
+	}
+	return nil
+}
+
+// afterSetValue is a flag.Value that calls after each time it is Set, and
+// returns its error.
+type afterSetValue struct {
+	transformValue
+	after func() error
+}
+
+func (v afterSetValue) Set(text string) error {
+	if err := v.transformValue.Set(text); err != nil {
+		return err
+	}
+	return v.after()
+}
+
+// afterSet wraps the Value of the flag name so that after is called each time
+// it is Set.
+func afterSet(fs FlagSet, name string, after func() error) {
+	noop := func(text string) (string, error) { return text, nil }
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(
+			afterSetValue{transformValue{f.Value, noop}, after}, f.Value)
+	case PFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(
+			afterSetValue{transformValue{f.Value, noop}, after}, f.Value)
+	}
+}
@@ -0,0 +1,110 @@
+package flagbind
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type groupServer struct {
+	Host string `flag:";localhost;Server host"`
+	Port int
+}
+
+func TestBindStructMap(t *testing.T) {
+	var f struct {
+		Servers map[string]groupServer  `flag:";;;keys=primary|backup"`
+		Ptrs    map[string]*groupServer `flag:"ptr"`
+	}
+	f.Ptrs = map[string]*groupServer{"b": {Port: 2}, "a": nil}
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	require.NoError(t, Bind(fs, &f))
+
+	// Unset flags store their defaults.
+	assert.Equal(t, map[string]groupServer{
+		"primary": {Host: "localhost"},
+		"backup":  {Host: "localhost"},
+	}, f.Servers)
+
+	require.NoError(t, fs.Parse([]string{
+		"-servers-primary-host", "example.com",
+		"-servers-backup-port", "8080",
+		"-ptr-a-port", "1",
+		"-ptr-b-host", "b.example.com",
+	}))
+	assert.Equal(t, map[string]groupServer{
+		"primary": {Host: "example.com"},
+		"backup":  {Host: "localhost", Port: 8080},
+	}, f.Servers)
+	assert.Equal(t, map[string]*groupServer{
+		"a": {Host: "localhost", Port: 1},
+		"b": {Host: "b.example.com", Port: 2},
+	}, f.Ptrs)
+
+	infos, err := Inspect(&f, MapKeys("Servers", "x"))
+	require.NoError(t, err)
+	var names, paths []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+		paths = append(paths, info.Path)
+	}
+	assert.Equal(t, []string{
+		"servers-x-host", "servers-x-port",
+		"ptr-a-host", "ptr-a-port",
+		"ptr-b-host", "ptr-b-port",
+	}, names)
+	assert.Equal(t, []string{
+		"Servers[x].Host", "Servers[x].Port",
+		"Ptrs[a].Host", "Ptrs[a].Port",
+		"Ptrs[b].Host", "Ptrs[b].Port",
+	}, paths)
+	assert.Len(t, f.Servers, 2, "Inspect must not modify v")
+
+	var bad struct {
+		Servers map[string]struct {
+			Port int `flag:";x"`
+		} `flag:";;;keys=a"`
+	}
+	pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	err = Bind(pfs, &bad)
+	require.Error(t, err)
+	assert.IsType(t, ErrorDefaultValue{}, err)
+	assert.Equal(t, "Servers[a].Port", err.(ErrorDefaultValue).FieldName)
+}
+
+func TestBindStructSlice(t *testing.T) {
+	var f struct {
+		Endpoints []groupServer `flag:";;;len=2"`
+		Ptrs      []*groupServer
+	}
+	f.Ptrs = []*groupServer{nil}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{
+		"--endpoints-0-host", "a.example.com",
+		"--endpoints-1-port", "8080",
+		"--ptrs-0-port", "1",
+	}))
+	assert.Equal(t, []groupServer{
+		{Host: "a.example.com"},
+		{Host: "localhost", Port: 8080},
+	}, f.Endpoints)
+	assert.Equal(t, []*groupServer{{Host: "localhost", Port: 1}}, f.Ptrs)
+
+	g := struct{ Endpoints []groupServer }{make([]groupServer, 1)}
+	infos, err := Inspect(&g)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "endpoints-0-host", infos[0].Name)
+	assert.Equal(t, "Endpoints[0].Host", infos[0].Path)
+	assert.Equal(t, groupServer{}, g.Endpoints[0],
+		"Inspect must not modify v")
+}
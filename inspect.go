@@ -0,0 +1,240 @@
+package flagbind
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagInfo describes a flag that Bind would define.
+type FlagInfo struct {
+	// Name and ShortName of the flag. ShortName is empty if the flag has
+	// none.
+	Name, ShortName string
+
+	// Default is the default value as displayed in the usage, and is empty
+	// when the default is hidden.
+	Default string
+
+	// Usage is the usage message, including any Extended Usage.
+	Usage string
+
+	// Options lists the Flag Tag options, such as "hidden" or
+	// "choices=json|yaml", as they appeared in the tag.
+	Options []string
+
+	// Path is the dotted path of the struct field that defines the flag,
+	// such as "HTTP.Timeout".
+	Path string
+
+	// Type is the type of the flag's Value, such as "int", "duration" or
+	// "stringSlice".
+	Type string
+
+	// Hidden is true if the flag is hidden from the usage.
+	Hidden bool
+
+	// NoOptDefVal is the value that the flag takes when it is used without
+	// a value, such as "true" for bool flags.
+	NoOptDefVal string
+}
+
+// Option returns the value of the named option and whether it was set. Options
+// without a value, such as "hidden", return an empty value.
+func (info FlagInfo) Option(name string) (string, bool) {
+	for _, opt := range info.Options {
+		val := ""
+		if i := strings.Index(opt, "="); i >= 0 {
+			opt, val = opt[:i], strings.TrimSpace(opt[i+1:])
+		}
+		if strings.EqualFold(strings.TrimSpace(opt), name) {
+			return val, true
+		}
+	}
+	return "", false
+}
+
+// Inspect returns a FlagInfo for each flag that Bind would define for v, with
+// the given opts, in the order that the flags would be defined. Inspect
+// returns the same errors as Bind.
+//
+// The flags are bound to a copy of v, so v is not modified, and File and Dir
+// fields are not checked, so no file or directory is required or created.
+// Flags are described as they are defined on a PFlagSet. WriteManPage and the
+// completion script writers are built on Inspect.
+func Inspect(v interface{}, opts ...Option) ([]FlagInfo, error) {
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+
+	b := newBind(opts...)
+	b.Values = nil
+	b.Unchecked = true
+	b.setNormalizeFunc(fs)
+	if err := b.bind(fs, copyStruct(v)); err != nil {
+		return nil, err
+	}
+	b.applyUsageFunc(fs)
+
+	infos := make([]FlagInfo, 0, len(b.State.Order))
+	for _, name := range b.State.Order {
+		if info, ok := b.State.flagInfo(fs, name); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// flagInfo returns the FlagInfo for the flag name in fs, if it is defined.
+func (s *bindState) flagInfo(fs FlagSet, name string) (FlagInfo, bool) {
+	info := FlagInfo{
+		Options: s.Tags[name].Options,
+		Path:    s.Flags[name],
+	}
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		if f == nil {
+			return info, false
+		}
+		info.Name = f.Name
+		info.Default = f.DefValue
+		info.Usage = f.Usage
+		if v, ok := f.Value.(interface{ Type() string }); ok {
+			info.Type = v.Type()
+		}
+		if isBoolFlag(f.Value) {
+			info.NoOptDefVal = "true"
+		}
+	case PFlagSet:
+		f := fs.Lookup(name)
+		if f == nil {
+			return info, false
+		}
+		info.Name = f.Name
+		info.ShortName = f.Shorthand
+		info.Default = f.DefValue
+		info.Usage = f.Usage
+		info.Type = f.Value.Type()
+		info.Hidden = f.Hidden
+		info.NoOptDefVal = f.NoOptDefVal
+	default:
+		return info, false
+	}
+	return info, true
+}
+
+// applyUsageFunc replaces the usage of each bound flag with the result of the
+// UsageFunc, if any.
+func (b bind) applyUsageFunc(fs FlagSet) {
+	if b.UsageFunc == nil {
+		return
+	}
+	for _, name := range b.State.Order {
+		info, ok := b.State.flagInfo(fs, name)
+		if !ok {
+			continue
+		}
+		usage := b.UsageFunc(info)
+		switch fs := fs.(type) {
+		case STDFlagSet:
+			fs.Lookup(name).Usage = usage
+		case PFlagSet:
+			fs.Lookup(name).Usage = usage
+		}
+	}
+}
+
+// copyStruct returns a pointer to a copy of the struct that v points to, along
+// with copies of anything that its exported fields point to, and of their maps
+// and slices, so that binding the copy does not modify v. Fields that share a
+// pointer still do so in the copy. Anything else is returned as is.
+func copyStruct(v interface{}) interface{} {
+	if _, ok := v.(Binder); ok {
+		return v
+	}
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() ||
+		ptr.Elem().Kind() != reflect.Struct {
+		return v
+	}
+	return copyPtr(ptr, make(map[fieldKey]reflect.Value)).Interface()
+}
+
+// copyPtr returns a pointer to a copy of what ptr points to, unless it has
+// already been copied.
+func copyPtr(ptr reflect.Value, copies map[fieldKey]reflect.Value) reflect.Value {
+	key := fieldKey{ptr.Pointer(), ptr.Type()}
+	if cp, ok := copies[key]; ok {
+		return cp
+	}
+	cp := reflect.New(ptr.Elem().Type())
+	copies[key] = cp
+	cp.Elem().Set(ptr.Elem())
+	if cp.Elem().Kind() == reflect.Struct {
+		copyFields(cp.Elem(), copies)
+	}
+	return cp
+}
+
+// copyFields replaces each non-nil pointer, including any held by an
+// interface, and each map and slice, in the exported fields of val with a
+// copy.
+func copyFields(val reflect.Value, copies map[fieldKey]reflect.Value) {
+	for i := 0; i < val.NumField(); i++ {
+		fieldV := val.Field(i)
+		if !fieldV.CanSet() {
+			continue
+		}
+		switch fieldV.Kind() {
+		case reflect.Struct:
+			copyFields(fieldV, copies)
+		case reflect.Ptr:
+			if fieldV.IsNil() {
+				continue
+			}
+			fieldV.Set(copyPtr(fieldV, copies))
+		case reflect.Interface:
+			elem := fieldV.Elem()
+			if !elem.IsValid() || elem.Kind() != reflect.Ptr ||
+				elem.IsNil() {
+				continue
+			}
+			fieldV.Set(copyPtr(elem, copies))
+		case reflect.Map:
+			if fieldV.IsNil() {
+				continue
+			}
+			cp := reflect.MakeMap(fieldV.Type())
+			for _, key := range fieldV.MapKeys() {
+				elem := fieldV.MapIndex(key)
+				if elem.Kind() == reflect.Ptr && !elem.IsNil() {
+					elem = copyPtr(elem, copies)
+				}
+				cp.SetMapIndex(key, elem)
+			}
+			fieldV.Set(cp)
+		case reflect.Slice:
+			if fieldV.IsNil() {
+				continue
+			}
+			cp := reflect.MakeSlice(fieldV.Type(),
+				fieldV.Len(), fieldV.Len())
+			reflect.Copy(cp, fieldV)
+			if !isStructSlice(fieldV.Type()) {
+				fieldV.Set(cp)
+				continue
+			}
+			for i := 0; i < cp.Len(); i++ {
+				elem := cp.Index(i)
+				if elem.Kind() == reflect.Struct {
+					copyFields(elem, copies)
+				} else if !elem.IsNil() {
+					elem.Set(copyPtr(elem, copies))
+				}
+			}
+			fieldV.Set(cp)
+		}
+	}
+}
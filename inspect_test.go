@@ -0,0 +1,110 @@
+package flagbind
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type inspectTestFlags struct {
+	Format  string        `flag:"format,f;json;Output format;choices=json|yaml"`
+	Verbose bool          `flag:";;Verbose output"`
+	Token   string        `flag:";abc;;secret,hidden"`
+	HTTP    *inspectHTTP  `flag:"http"`
+	Wait    time.Duration `flag:";;;hide-default"`
+}
+
+type inspectHTTP struct {
+	Timeout time.Duration `flag:";5s;HTTP timeout"`
+}
+
+func TestInspect(t *testing.T) {
+	http := &inspectHTTP{}
+	f := inspectTestFlags{HTTP: http}
+	infos, err := Inspect(&f)
+	require.NoError(t, err)
+
+	assert.Equal(t, []FlagInfo{{
+		Name:      "format",
+		ShortName: "f",
+		Default:   "json",
+		Usage:     "Output format",
+		Options:   []string{"choices=json|yaml"},
+		Path:      "Format",
+		Type:      "string",
+	}, {
+		Name:        "verbose",
+		Default:     "false",
+		Usage:       "Verbose output",
+		Path:        "Verbose",
+		Type:        "bool",
+		NoOptDefVal: "true",
+	}, {
+		Name:    "token",
+		Default: "***",
+		Options: []string{"secret", "hidden"},
+		Path:    "Token",
+		Type:    "secret",
+		Hidden:  true,
+	}, {
+		Name:    "http-timeout",
+		Default: "5s",
+		Usage:   "HTTP timeout",
+		Path:    "HTTP.Timeout",
+		Type:    "duration",
+	}, {
+		Name:    "wait",
+		Options: []string{"hide-default"},
+		Path:    "Wait",
+		Type:    "duration",
+	}}, infos)
+
+	choices, ok := infos[0].Option("choices")
+	assert.True(t, ok)
+	assert.Equal(t, "json|yaml", choices)
+	_, ok = infos[0].Option("hidden")
+	assert.False(t, ok)
+
+	// v is not modified.
+	assert.Equal(t, inspectTestFlags{HTTP: http}, f)
+	assert.Equal(t, inspectHTTP{}, *http)
+
+	_, err = Inspect(f)
+	assert.Error(t, err)
+}
+
+func TestInspectNoSideEffects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flagbind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Setenv("FLAGBIND_TEST_DIR", dir))
+	defer os.Unsetenv("FLAGBIND_TEST_DIR")
+
+	n, s := 0, ""
+	labels := map[string]string{"a": "1"}
+	f := struct {
+		N      *int              `flag:";5"`
+		S      *string           `flag:";text"`
+		Labels map[string]string `flag:";b=2;;merge"`
+		Out    Dir               `flag:";$FLAGBIND_TEST_DIR/out;;create,expand-env"`
+		Cache  Dir               `flag:";flagbind-missing-dir"`
+	}{N: &n, S: &s, Labels: labels}
+	_, err = Inspect(&f)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, n)
+	assert.Equal(t, "", s)
+	assert.Equal(t, map[string]string{"a": "1"}, labels)
+	assert.NoDirExists(t, filepath.Join(dir, "out"))
+
+	// Bind still creates the directory.
+	f.Cache = Dir(dir)
+	require.NoError(t, Bind(flag.NewFlagSet("", flag.ContinueOnError), &f))
+	assert.DirExists(t, filepath.Join(dir, "out"))
+}
@@ -0,0 +1,51 @@
+package flagbind
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// sizedIntValue is a flag.Value for the standard flag package for the integer
+// widths that it does not support natively. Set is range checked against the
+// size of the underlying type.
+type sizedIntValue struct {
+	value reflect.Value // Elem of *int8, *uint16, etc.
+}
+
+func newSizedIntValue(ptr interface{}) *sizedIntValue {
+	return &sizedIntValue{reflect.ValueOf(ptr).Elem()}
+}
+
+func (val *sizedIntValue) Set(text string) error {
+	bits := val.value.Type().Bits()
+	switch val.value.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		i, err := strconv.ParseInt(text, 0, bits)
+		if err != nil {
+			return err
+		}
+		val.value.SetInt(i)
+	default:
+		u, err := strconv.ParseUint(text, 0, bits)
+		if err != nil {
+			return err
+		}
+		val.value.SetUint(u)
+	}
+	return nil
+}
+
+func (val *sizedIntValue) String() string {
+	if !val.value.IsValid() {
+		return "0"
+	}
+	return fmt.Sprint(val.value.Interface())
+}
+
+func (val *sizedIntValue) Type() string {
+	if !val.value.IsValid() {
+		return ""
+	}
+	return val.value.Kind().String()
+}
@@ -0,0 +1,78 @@
+package flagbind
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Interval is a polling interval with an optional random jitter. It is a
+// flag.Value that parses the form `<interval>[±<jitter>]`, for example "30s±5s".
+// An ASCII "+-" may be used in place of "±".
+type Interval struct {
+	Interval time.Duration
+	Jitter   time.Duration
+}
+
+func (i *Interval) Set(text string) error {
+	text = strings.Replace(text, "+-", "±", 1)
+	parts := strings.SplitN(text, "±", 2)
+
+	interval, err := time.ParseDuration(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return err
+	}
+
+	var jitter time.Duration
+	if len(parts) == 2 {
+		jitter, err = time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return err
+		}
+		if jitter < 0 {
+			return fmt.Errorf("jitter must not be negative")
+		}
+		if jitter > maxJitter {
+			return fmt.Errorf("jitter must not exceed %v", maxJitter)
+		}
+	}
+
+	*i = Interval{interval, jitter}
+	return nil
+}
+
+func (i Interval) String() string {
+	if i.Jitter == 0 {
+		return i.Interval.String()
+	}
+	return i.Interval.String() + "±" + i.Jitter.String()
+}
+
+func (i Interval) Type() string { return "interval" }
+
+// maxJitter is the largest Jitter for which the range ±Jitter fits in an
+// int64.
+const maxJitter = time.Duration(math.MaxInt64 / 2)
+
+// Next returns the Interval offset by a random amount within ±Jitter. The
+// result is never negative. A Jitter above maxJitter, which Set rejects, is
+// treated as maxJitter.
+func (i Interval) Next() time.Duration {
+	next := i.Interval
+	if jitter := i.Jitter; jitter > 0 {
+		if jitter > maxJitter {
+			jitter = maxJitter
+		}
+		offset := time.Duration(rand.Int63n(int64(2*jitter)+1)) - jitter
+		if offset > 0 && next > math.MaxInt64-offset {
+			return math.MaxInt64
+		}
+		next += offset
+	}
+	if next < 0 {
+		return 0
+	}
+	return next
+}
@@ -0,0 +1,38 @@
+package flagbind
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalNext(t *testing.T) {
+	assert := assert.New(t)
+	i := Interval{30 * time.Second, 5 * time.Second}
+	for n := 0; n < 100; n++ {
+		next := i.Next()
+		assert.True(next >= 25*time.Second && next <= 35*time.Second, next)
+	}
+
+	i = Interval{time.Second, 0}
+	assert.Equal(time.Second, i.Next())
+
+	i = Interval{0, time.Second}
+	assert.True(i.Next() >= 0)
+
+	i = Interval{math.MaxInt64, math.MaxInt64}
+	for n := 0; n < 100; n++ {
+		assert.True(i.Next() >= 0)
+	}
+}
+
+func TestIntervalSetJitter(t *testing.T) {
+	assert := assert.New(t)
+	var i Interval
+	assert.EqualError(i.Set("1s±2562047h"),
+		"jitter must not exceed 1281023h53m38.427387903s")
+	assert.NoError(i.Set("1s±1281023h"))
+	assert.True(i.Next() >= 0)
+}
@@ -0,0 +1,24 @@
+package flagbind
+
+import "net/mail"
+
+// MailAddress is a mail.Address that is parsed using mail.ParseAddress.
+type MailAddress mail.Address
+
+func (a *MailAddress) Set(text string) error {
+	addr, err := mail.ParseAddress(text)
+	if err != nil {
+		return err
+	}
+	*a = MailAddress(*addr)
+	return nil
+}
+
+func (a MailAddress) String() string {
+	if a == (MailAddress{}) {
+		return ""
+	}
+	return (*mail.Address)(&a).String()
+}
+
+func (a MailAddress) Type() string { return "email" }
@@ -0,0 +1,182 @@
+package flagbind
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// ManPage is the metadata for a man page generated by WriteManPage.
+type ManPage struct {
+	// Name of the command. If empty, the <name> of a Command Tag is
+	// used.
+	Name string
+	// Section of the manual. The default is "1".
+	Section string
+	// Short is a one line description of the command. If empty, the
+	// <short> of a Command Tag is used.
+	Short string
+	// Long is the DESCRIPTION, which may contain multiple paragraphs
+	// separated by blank lines.
+	Long string
+
+	// Date, Source, and Manual are the optional footer and header fields.
+	Date, Source, Manual string
+
+	// STDFlag documents the flags as they are used with the standard flag
+	// package, with a single dash and no short names, instead of pflag.
+	STDFlag bool
+}
+
+// WriteManPage writes a troff man page documenting the flags that Bind would
+// define for v, with the given opts, to w.
+//
+//
+// Command Tag
+//
+// The command name and short description may be set on v with a `command` tag
+// on a blank identifier field:
+//
+//      type Flags struct {
+//              _ struct{} `command:"<name>;<short description>"`
+//      }
+func WriteManPage(w io.Writer, v interface{}, page ManPage, opts ...Option) error {
+	infos, err := Inspect(v, opts...)
+	if err != nil {
+		return err
+	}
+
+	name, short := commandTag(v)
+	if page.Name == "" {
+		page.Name = name
+	}
+	if page.Short == "" {
+		page.Short = short
+	}
+	if page.Section == "" {
+		page.Section = "1"
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, ".TH %v %v %v %v %v\n",
+		manQuote(strings.ToUpper(page.Name)), manQuote(page.Section),
+		manQuote(page.Date), manQuote(page.Source), manQuote(page.Manual))
+
+	fmt.Fprintf(bw, ".SH NAME\n%v", manEscape(page.Name))
+	if page.Short != "" {
+		fmt.Fprintf(bw, " \\- %v", manEscape(page.Short))
+	}
+	fmt.Fprintf(bw, "\n.SH SYNOPSIS\n.B %v\n[\\fIOPTIONS\\fR]\n",
+		manEscape(page.Name))
+
+	if page.Long != "" {
+		fmt.Fprintf(bw, ".SH DESCRIPTION\n")
+		for i, par := range strings.Split(page.Long, "\n\n") {
+			if i > 0 {
+				fmt.Fprintf(bw, ".PP\n")
+			}
+			fmt.Fprintf(bw, "%v\n", manEscape(strings.TrimSpace(par)))
+		}
+	}
+
+	fmt.Fprintf(bw, ".SH OPTIONS\n")
+	for _, info := range infos {
+		if info.Hidden {
+			continue
+		}
+		fmt.Fprintf(bw, ".TP\n")
+		if info.ShortName != "" && !page.STDFlag {
+			fmt.Fprintf(bw, "\\fB\\-%v\\fR, ", manEscape(info.ShortName))
+		}
+		dash := "\\-\\-"
+		if page.STDFlag {
+			dash = "\\-"
+		}
+		fmt.Fprintf(bw, "\\fB%v%v\\fR", dash, manEscape(info.Name))
+		varname, usage := pflag.UnquoteUsage(&pflag.Flag{
+			Usage: info.Usage, Value: typeValue(info.Type)})
+		if varname != "" {
+			fmt.Fprintf(bw, " \\fI%v\\fR", manEscape(varname))
+		}
+		fmt.Fprintf(bw, "\n%v", manEscape(usage))
+		if !isZeroDefault(info.Default) {
+			fmt.Fprintf(bw, " (default %v)", manEscape(info.Default))
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+
+	return bw.Flush()
+}
+
+// commandTag returns the <name> and <short description> of the Command Tag
+// on v, if any.
+func commandTag(v interface{}) (name, short string) {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("command")
+		if !ok {
+			continue
+		}
+		args := strings.SplitN(tag, ";", 2)
+		name = args[0]
+		if len(args) > 1 {
+			short = strings.TrimSpace(args[1])
+		}
+		return
+	}
+	return
+}
+
+// isZeroDefault reports whether def is the zero value of its type, in which
+// case it is not displayed.
+func isZeroDefault(def string) bool {
+	switch def {
+	case "", "0", "false", "[]", "<nil>", "0s", "map[]":
+		return true
+	}
+	return false
+}
+
+// typeValue is a pflag.Value that only reports its Type, for use with
+// pflag.UnquoteUsage.
+type typeValue string
+
+func (typeValue) String() string   { return "" }
+func (typeValue) Set(string) error { return nil }
+func (t typeValue) Type() string   { return string(t) }
+
+// manEscape escapes text for use in a troff document.
+func manEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		// A line may not begin with a control character.
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// manQuote returns text as a double quoted argument to a troff macro, which
+// may not span lines.
+func manQuote(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "\n", " ")
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
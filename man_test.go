@@ -0,0 +1,45 @@
+package flagbind
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteManPage(t *testing.T) {
+	var f struct {
+		_       struct{} `command:"serve;Serve files over HTTP"`
+		Addr    string   `flag:"addr,a;:8080;Address to listen on"`
+		Dir     string   "flag:\";;Serve files from `path`\""
+		Verbose bool
+		Secret  bool `flag:";;;hidden"`
+	}
+	var man bytes.Buffer
+	require.NoError(t, WriteManPage(&man, &f, ManPage{
+		Date:   "2020",
+		Manual: `The "Serve" Manual \ v1`,
+		Long:   "Serve starts a server.\n\n.Dots must be escaped.",
+	}))
+	assert.Equal(t, `.TH "SERVE" "1" "2020" "" "The ""Serve"" Manual \e v1"
+.SH NAME
+serve \- Serve files over HTTP
+.SH SYNOPSIS
+.B serve
+[\fIOPTIONS\fR]
+.SH DESCRIPTION
+Serve starts a server.
+.PP
+\&.Dots must be escaped.
+.SH OPTIONS
+.TP
+\fB\-a\fR, \fB\-\-addr\fR \fIstring\fR
+Address to listen on (default :8080)
+.TP
+\fB\-\-dir\fR \fIpath\fR
+Serve files from path
+.TP
+\fB\-\-verbose\fR
+`+"\n", man.String())
+}
@@ -0,0 +1,186 @@
+package flagbind
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// stringToStringValue is a map[string]string flag.Value that mirrors pflag's
+// StringToString. Each flag occurrence accepts one
+// or more comma separated key=value pairs. The first occurrence replaces any
+// default, and subsequent occurrences accumulate. If merge is set, the first
+// occurrence also accumulates into the default.
+type stringToStringValue struct {
+	value   *map[string]string
+	changed bool
+	merge   bool
+}
+
+func (s *stringToStringValue) Set(text string) error {
+	var pairs []string
+	switch strings.Count(text, "=") {
+	case 0:
+		return fmt.Errorf("%s must be formatted as key=value", text)
+	case 1:
+		pairs = append(pairs, strings.Trim(text, `"`))
+	default:
+		var err error
+		pairs, err = csv.NewReader(strings.NewReader(text)).Read()
+		if err != nil {
+			return err
+		}
+	}
+	out := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("%s must be formatted as key=value", pair)
+		}
+		out[kv[0]] = kv[1]
+	}
+	if !(s.changed || s.merge) || *s.value == nil {
+		*s.value = out
+	} else {
+		for k, v := range out {
+			(*s.value)[k] = v
+		}
+	}
+	s.changed = true
+	return nil
+}
+
+func (s *stringToStringValue) String() string {
+	if s.value == nil || len(*s.value) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(*s.value))
+	for k, v := range *s.value {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(pairs)
+	w.Flush()
+	return "[" + strings.TrimSpace(buf.String()) + "]"
+}
+
+func (s *stringToStringValue) save() func() {
+	saved, changed := copyMap(reflect.ValueOf(*s.value)), s.changed
+	return func() {
+		*s.value = copyMap(saved).Interface().(map[string]string)
+		s.changed = changed
+	}
+}
+
+func (s *stringToStringValue) Type() string { return "stringToString" }
+
+// textMapValue is a flag.Value for a map with string keys and values that
+// implement encoding.TextUnmarshaler. Each flag occurrence accepts a single
+// key=value pair, where the value is passed to UnmarshalText. The first
+// occurrence replaces any default, and subsequent occurrences accumulate. If
+// merge is set, the first occurrence also accumulates into the default.
+type textMapValue struct {
+	value   reflect.Value // *map[K]V
+	changed bool
+	merge   bool
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isTextMap reports whether t is a map type with string keys and values that
+// implement encoding.TextUnmarshaler through a pointer.
+func isTextMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map &&
+		t.Key().Kind() == reflect.String &&
+		reflect.PtrTo(t.Elem()).Implements(textUnmarshalerType)
+}
+
+func newTextMapValue(ptr reflect.Value, merge bool) *textMapValue {
+	return &textMapValue{value: ptr, merge: merge}
+}
+
+func (m *textMapValue) Set(text string) error {
+	kv := strings.SplitN(text, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("%s must be formatted as key=value", text)
+	}
+
+	mapT := m.value.Type().Elem()
+	elem := reflect.New(mapT.Elem())
+	if err := elem.Interface().(encoding.TextUnmarshaler).
+		UnmarshalText([]byte(kv[1])); err != nil {
+		return err
+	}
+
+	mapV := m.value.Elem()
+	if !(m.changed || m.merge) || mapV.IsNil() {
+		mapV.Set(reflect.MakeMap(mapT))
+	}
+	mapV.SetMapIndex(reflect.ValueOf(kv[0]).Convert(mapT.Key()), elem.Elem())
+	m.changed = true
+	return nil
+}
+
+func (m *textMapValue) String() string {
+	if !m.value.IsValid() || m.value.Elem().Len() == 0 {
+		return ""
+	}
+	mapV := m.value.Elem()
+	pairs := make([]string, 0, mapV.Len())
+	iter := mapV.MapRange()
+	for iter.Next() {
+		pairs = append(pairs, fmt.Sprintf("%v=%v",
+			iter.Key(), textString(iter.Value())))
+	}
+	sort.Strings(pairs)
+	return "[" + strings.Join(pairs, ",") + "]"
+}
+
+func (m *textMapValue) save() func() {
+	saved, changed := copyMap(m.value.Elem()), m.changed
+	return func() {
+		m.value.Elem().Set(copyMap(saved))
+		m.changed = changed
+	}
+}
+
+func (m *textMapValue) Type() string {
+	if !m.value.IsValid() {
+		return ""
+	}
+	return m.value.Type().Elem().String()
+}
+
+// textString returns the MarshalText result of v if it implements
+// encoding.TextMarshaler, otherwise it is formatted with fmt.
+func textString(v reflect.Value) string {
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	if marshaler, ok := ptr.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "<invalid>"
+		}
+		return string(text)
+	}
+	return fmt.Sprint(v)
+}
+
+// copyMap returns a copy of the map v, or v if it is nil.
+func copyMap(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		return v
+	}
+	dst := reflect.MakeMapWithSize(v.Type(), v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		dst.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return dst
+}
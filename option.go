@@ -1,27 +1,302 @@
 package flagbind
 
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/pflag"
+)
+
 func newBind(opts ...Option) bind {
 	var b bind
 	for _, opt := range opts {
 		opt(&b)
 	}
+	if b.State == nil {
+		b.State = newBindState()
+	}
 	return b
 }
 
 type bind struct {
 	Prefix        string
 	NoAutoFlatten bool
+	Splitter      Splitter
+	NameFunc      func(fieldName string) string
+
+	// NameTags are the struct tag keys that flag names may be derived
+	// from.
+	NameTags []string
+
+	// JSONNames uses `json` tag names as flag names.
+	JSONNames bool
+
+	// ValueSource is consulted for the default of each flag.
+	ValueSource ValueSource
+
+	// Values synchronizes access to each bound field.
+	Values *Values
+
+	// NoDefaults binds the current field values without applying any
+	// defaults, for DumpValues.
+	NoDefaults bool
+
+	// Unchecked binds File and Dir fields without the checks and side
+	// effects of their Set, for Inspect.
+	Unchecked bool
+
+	// NormalizeFunc is installed on a *pflag.FlagSet.
+	NormalizeFunc func(f *pflag.FlagSet, name string) pflag.NormalizedName
+
+	// Separator overrides the package level Separator, if HasSeparator.
+	Separator    string
+	HasSeparator bool
+
+	StrictShortNames bool
+	RequireTags      bool
+	IgnoreUntagged   bool
+
+	DeclarationOrder bool
+	UsageWidth       int
+	HideZeroDefaults bool
+	UsageFunc        func(FlagInfo) string
+	UsageTemplate    *template.Template
+	Translate        func(key string) string
+
+	// ExtendedDurations parses time.Duration flags with ParseDuration.
+	ExtendedDurations bool
+
+	// IncludePrefixes, ExcludePrefixes, OnlyNames, and ExceptNames filter
+	// the flags that are defined by their full names.
+	IncludePrefixes []string
+	ExcludePrefixes []string
+	OnlyNames       []string
+	ExceptNames     []string
+
+	// SkipExisting skips flags that are already defined in the FlagSet.
+	SkipExisting bool
+
+	// OverrideExisting redefines flags that are already defined in the
+	// FlagSet.
+	OverrideExisting bool
+
+	// OnRename is called for each flag renamed by RenameCollisions.
+	OnRename func(Rename)
+
+	// ExpandDefaults expands environment variables and a leading ~ in
+	// Flag Tag <default> values.
+	ExpandDefaults bool
+
+	// ErrorHandling is applied to errors returned by Bind.
+	ErrorHandling flag.ErrorHandling
+
+	// MapKeys maps struct field paths to the keys to bind for a map of
+	// structs.
+	MapKeys map[string][]string
+
+	// Path is the dotted struct field path up to the current struct.
+	Path string
+
+	// Nested is true for calls to Bind made by a Binder.
+	Nested bool
+
+	// Context is set by BindContext.
+	Context context.Context
+
+	// State is shared by all recursive calls to bind, including those
+	// made through Binder implementations which pass along Option().
+	State *bindState
+}
+
+// bindState tracks what has been bound over the course of a single call to
+// Bind.
+type bindState struct {
+	// Fields maps the address and type of each bound field to its struct
+	// field path so that aliased memory may be detected.
+	Fields map[fieldKey]string
+
+	// Flags maps each bound flag name to its struct field path.
+	Flags map[string]string
+
+	// Order lists the bound flag names in the order they were defined.
+	Order []string
+
+	// ShortNames maps flag names to the short names set by Overriding Flag
+	// Tags, which must be applied when the flag is defined.
+	ShortNames map[string]string
+
+	// Tags maps the name of each flag bound from a struct field to its tag.
+	Tags map[string]flagTag
+
+	// Origins lists the flags whose values did not come from their Flag
+	// Tag <default>, with their Origin.
+	Origins []flagOrigin
+
+	// Existing is the set of flags that were defined in fs before Bind
+	// was called, which are never attributed to a field by recordFlags.
+	Existing map[string]bool
+
+	// Scratch is the FlagSet that Flag Tag <default> values are parsed
+	// with before each flag is defined.
+	Scratch FlagSet
+}
+
+type flagOrigin struct {
+	Name   string
+	Origin Origin
+}
+
+type fieldKey struct {
+	Addr uintptr
+	Type reflect.Type
+}
+
+func newBindState() *bindState {
+	return &bindState{
+		Fields: make(map[fieldKey]string),
+		Flags:  make(map[string]string),
+		Tags:   make(map[string]flagTag),
+	}
+}
+
+// recordFlags attributes any flags in fs that have not yet been recorded, and
+// were not defined before Bind was called, to the field path.
+func (s *bindState) recordFlags(fs FlagSet, path string) {
+	for _, name := range flagNames(fs) {
+		if _, ok := s.Flags[name]; !ok && !s.Existing[name] {
+			s.addFlag(name, path)
+		}
+	}
+}
+
+// recordExisting records the flags already defined in fs.
+func (s *bindState) recordExisting(fs FlagSet) {
+	for _, name := range flagNames(fs) {
+		if s.Existing == nil {
+			s.Existing = make(map[string]bool)
+		}
+		s.Existing[name] = true
+	}
+}
+
+// scratch returns the Scratch FlagSet, from the same package as fs, creating
+// it if needed.
+func (s *bindState) scratch(fs FlagSet) FlagSet {
+	_, isPFlag := fs.(PFlagSet)
+	if _, ok := s.Scratch.(PFlagSet); s.Scratch == nil || ok != isPFlag {
+		s.Scratch = newFlagSetLike(fs)
+	}
+	return s.Scratch
+}
+
+// separator returns the Separator for this call to Bind.
+func (b bind) separator() string {
+	if b.HasSeparator {
+		return b.Separator
+	}
+	return Separator
+}
+
+// addFlag records that the flag name was bound to the field path.
+func (s *bindState) addFlag(name, path string) {
+	s.Flags[name] = path
+	s.Order = append(s.Order, name)
+}
+
+// addOverrideShortNames records the short names set by any Overriding Flag
+// Tags in fields.
+func (s *bindState) addOverrideShortNames(fields []cachedField) {
+	for _, field := range fields {
+		if !field.IsMetadata || !field.HasTag ||
+			!isOverrideShortName(field.Tag) {
+			continue
+		}
+		if s.ShortNames == nil {
+			s.ShortNames = make(map[string]string)
+		}
+		s.ShortNames[field.Tag.Name] = field.Tag.ShortName
+	}
+}
+
+// isOverrideShortName reports whether the Overriding Flag Tag sets a short
+// name.
+func isOverrideShortName(tag flagTag) bool {
+	return tag.ShortName != "" && tag.Name != tag.ShortName
+}
+
+// addOrigin records the Origin of the value of the flag name.
+func (s *bindState) addOrigin(name string, origin Origin) {
+	s.Origins = append(s.Origins, flagOrigin{name, origin})
+}
+
+// hideFlags hides the flags bound since the first order flags, if fs is a
+// PFlagSet.
+func (s *bindState) hideFlags(fs FlagSet, order int) {
+	pfs, ok := fs.(PFlagSet)
+	if !ok {
+		return
+	}
+	for _, name := range s.Order[order:] {
+		pfs.Lookup(name).Hidden = true
+	}
+}
+
+// fieldPath returns the dotted path to the field with the given name.
+func (b bind) fieldPath(name string) string {
+	if b.Path == "" {
+		return name
+	}
+	return b.Path + "." + name
+}
+
+// context returns the Context, or context.Background() if it is not set.
+func (b bind) context() context.Context {
+	if b.Context == nil {
+		return context.Background()
+	}
+	return b.Context
+}
+
+// OptionContext returns the context.Context that BindContext was called with,
+// given the opt passed to a Binder or FieldBinder, so that implementations may
+// observe cancellation and deadlines. It returns context.Background() if the
+// opt is from Bind.
+func OptionContext(opt Option) context.Context {
+	var b bind
+	opt(&b)
+	return b.context()
 }
 
 func (b bind) Option() Option {
 	return func(bb *bind) {
 		*bb = b
+		bb.Nested = true
 	}
 }
 
 // Option is an option that may be passed to Bind.
 type Option func(*bind)
 
+// MapKeys sets the keys to bind for the map of structs at the dotted struct
+// field path, such as "Upstream.Servers". This takes precedence over the
+// `keys` tag option. See Maps of Structs in the Bind documentation.
+func MapKeys(path string, keys ...string) Option {
+	return func(b *bind) {
+		mapKeys := make(map[string][]string, len(b.MapKeys)+1)
+		for p, k := range b.MapKeys {
+			mapKeys[p] = k
+		}
+		mapKeys[path] = keys
+		b.MapKeys = mapKeys
+	}
+}
+
 // Prefix all flag names with prefix, which should include any final separator
 // (e.g. 'http-' or 'http.')
 func Prefix(prefix string) Option {
@@ -41,3 +316,386 @@ func NoAutoFlatten() Option {
 		b.NoAutoFlatten = true
 	}
 }
+
+// NameSplitter sets the Splitter used to derive flag names from field names. The
+// default is CamelCaseSplitter.
+func NameSplitter(s Splitter) Option {
+	return func(b *bind) {
+		b.Splitter = s
+	}
+}
+
+// NameFunc sets a func that derives flag names from field names, in place of
+// the NameSplitter and separator. For example, it may strip a suffix such as
+// "Config" before calling FromCamelCase. Flags with an explicit name in their
+// Flag Tag are not affected.
+func NameFunc(fn func(fieldName string) string) Option {
+	return func(b *bind) {
+		b.NameFunc = fn
+	}
+}
+
+// NameTags derives the flag name of a field without an explicit name in its
+// Flag Tag from the first of the struct tags with the given keys that is set,
+// such as "mapstructure" or "envconfig", before falling back to the
+// NameFunc or NameSplitter. The tag value is converted to kebab-case, using
+// the separator, so `mapstructure:"listen_addr"` and `envconfig:"LISTEN_ADDR"`
+// both bind --listen-addr.
+func NameTags(keys ...string) Option {
+	return func(b *bind) {
+		b.NameTags = keys
+	}
+}
+
+// UseJSONNames uses the name in the `json` tag of a field without an explicit
+// name in its Flag Tag as its flag name, exactly as it is written, so that
+// flags, config files, and API payloads share one spelling. For example,
+// `json:"listen_addr"` binds --listen_addr. This takes precedence over
+// NameTags.
+func UseJSONNames() Option {
+	return func(b *bind) {
+		b.JSONNames = true
+	}
+}
+
+// PFlagNormalizeFunc installs fn on the FlagSet with SetNormalizeFunc before
+// any flags are defined, if the FlagSet is a *pflag.FlagSet, so that flag names
+// are normalized when they are defined and parsed. For example, fn may
+// replace "_" with "-" so that both are accepted.
+func PFlagNormalizeFunc(
+	fn func(f *pflag.FlagSet, name string) pflag.NormalizedName) Option {
+	return func(b *bind) {
+		b.NormalizeFunc = fn
+	}
+}
+
+// setNormalizeFunc installs the NormalizeFunc on fs, if it is a
+// *pflag.FlagSet.
+func (b bind) setNormalizeFunc(fs FlagSet) {
+	if pfs, ok := fs.(*pflag.FlagSet); ok && b.NormalizeFunc != nil {
+		pfs.SetNormalizeFunc(b.NormalizeFunc)
+	}
+}
+
+// VerbatimNames causes flag names to be derived from field names exactly as
+// they are written, such as "StringFlag", instead of in kebab-case. Nested
+// prefixes are still joined by the separator. This is equivalent to a
+// NameSplitter that does not split.
+func VerbatimNames() Option {
+	return NameSplitter(SplitterFunc(func(name string) []string {
+		return []string{name}
+	}))
+}
+
+// SeparatorOpt sets the separator used between a prefix and a flag name and
+// between the words of a flag name, in place of the package level Separator.
+// Unlike Separator, it is safe to use concurrently with other calls to Bind.
+func SeparatorOpt(sep string) Option {
+	return func(b *bind) {
+		b.Separator = sep
+		b.HasSeparator = true
+	}
+}
+
+// DeclarationOrder causes the usage to list flags in the order they were
+// declared in the struct, instead of alphabetically. Flags not defined by Bind
+// are listed last, alphabetically.
+//
+// With pflag, this sets SortFlags to false, so other flags are listed in the
+// order they were defined. With the standard flag package, this sets the
+// Usage of the FlagSet, which must be a *flag.FlagSet.
+func DeclarationOrder() Option {
+	return func(b *bind) {
+		b.DeclarationOrder = true
+	}
+}
+
+// UsageWidth wraps the usage of flags at spaces so that usage lines fit within
+// cols columns. The FlagSet must be a *flag.FlagSet or *pflag.FlagSet. With
+// pflag, this sets the Usage of the FlagSet to print FlagUsagesWrapped(cols),
+// which also wraps flags defined after Bind. With the flag package, newlines
+// are inserted into the Usage of each flag defined by Bind, which are indented
+// by PrintDefaults, and a "(default ...)" may extend the last line.
+func UsageWidth(cols int) Option {
+	return func(b *bind) {
+		b.UsageWidth = cols
+	}
+}
+
+// HideZeroDefaults hides the default in the usage of every flag whose field
+// has the zero value of its type when Bind returns, as if each had the
+// `skip-zero-default` tag option. This avoids noise such as `(default [])`.
+func HideZeroDefaults() Option {
+	return func(b *bind) {
+		b.HideZeroDefaults = true
+	}
+}
+
+// ExpandDefaults expands environment variables, such as $XDG_CACHE_HOME, and a
+// leading "~/" to the home directory, in each Flag Tag <default> when Bind is
+// called, such as `flag:"cache-dir;$XDG_CACHE_HOME/app"` or
+// `flag:"data;~/data"`. The expanded default is displayed in the usage.
+func ExpandDefaults() Option {
+	return func(b *bind) {
+		b.ExpandDefaults = true
+	}
+}
+
+// UsageFunc sets a func that returns the usage of each flag defined by Bind,
+// given its FlagInfo, so that every usage may be decorated consistently, such
+// as by appending "(required)". It is called once Bind has defined every
+// flag, and before the UsageWidth is applied.
+func UsageFunc(fn func(FlagInfo) string) Option {
+	return func(b *bind) {
+		b.UsageFunc = fn
+	}
+}
+
+// UsageTemplate sets the Usage func of fs, if it is a *flag.FlagSet or
+// *pflag.FlagSet, to execute tmpl with UsageData, so that the whole usage
+// output may be rendered in any style. The usage is written to the Output of
+// a *flag.FlagSet, or else to os.Stderr. Add UsageFuncs to tmpl before it is
+// parsed for helpers such as "option" and "env". For example:
+//
+//      tmpl := template.Must(template.New("usage").
+//              Funcs(flagbind.UsageFuncs()).Parse(`Usage of {{.Name}}:
+//      {{range .Flags}}{{if not .Hidden}}  --{{.Name}}  {{.Usage}}
+//      {{end}}{{end}}`))
+//      err := flagbind.Bind(fs, &flags, flagbind.UsageTemplate(tmpl))
+func UsageTemplate(tmpl *template.Template) Option {
+	return func(b *bind) {
+		b.UsageTemplate = tmpl
+	}
+}
+
+// Translate sets a func that translates the usage of each flag defined by
+// Bind, including any Extended Usage, and the usage set by Overriding Flag
+// Tags. The usage from the tag is passed as the message key, and is kept if fn
+// returns "". Flags defined by a Binder are not translated.
+func Translate(fn func(key string) string) Option {
+	return func(b *bind) {
+		b.Translate = fn
+	}
+}
+
+// ExtendedDurations causes Bind to parse time.Duration fields, including
+// their Flag Tag <default>, with ParseDuration, which also accepts days and
+// weeks, such as "7d" or "1w2d". Values are still displayed like
+// time.Duration, such as "168h0m0s".
+func ExtendedDurations() Option {
+	return func(b *bind) {
+		b.ExtendedDurations = true
+	}
+}
+
+// IncludePrefix causes Bind to only define the flags whose full names, including
+// any prefix, begin with one of the prefixes passed to IncludePrefix, so that
+// a large shared flag struct may be partially bound. It may be used more than
+// once. ExcludePrefix and Except take precedence. Flags defined by a Binder
+// are not filtered, and an Overriding Flag Tag for a flag that is not defined
+// is ignored.
+func IncludePrefix(prefixes ...string) Option {
+	return func(b *bind) {
+		b.IncludePrefixes = append(b.IncludePrefixes, prefixes...)
+	}
+}
+
+// ExcludePrefix causes Bind to not define any flag whose full name, including
+// any prefix, begins with one of prefixes. It may be used more than once. See
+// IncludePrefix.
+func ExcludePrefix(prefixes ...string) Option {
+	return func(b *bind) {
+		b.ExcludePrefixes = append(b.ExcludePrefixes, prefixes...)
+	}
+}
+
+// Only causes Bind to only define the flags with the given full names,
+// including any prefix, such as Only("timeout", "retries"). It may be used
+// more than once, and with IncludePrefix, in which case a flag is defined if
+// it is named by either. See IncludePrefix.
+func Only(names ...string) Option {
+	return func(b *bind) {
+		b.OnlyNames = append(b.OnlyNames, names...)
+	}
+}
+
+// Except causes Bind to not define the flags with the given full names,
+// including any prefix, such as Except("legacy-mode"). It may be used more
+// than once. See IncludePrefix.
+func Except(names ...string) Option {
+	return func(b *bind) {
+		b.ExceptNames = append(b.ExceptNames, names...)
+	}
+}
+
+// hasFlagFilter reports whether any of the IncludePrefix, ExcludePrefix, Only,
+// or Except Options are used.
+func (b bind) hasFlagFilter() bool {
+	return len(b.IncludePrefixes) > 0 || len(b.ExcludePrefixes) > 0 ||
+		len(b.OnlyNames) > 0 || len(b.ExceptNames) > 0
+}
+
+// includeFlag reports whether the flag name passes the IncludePrefix,
+// ExcludePrefix, Only, and Except Options.
+func (b bind) includeFlag(name string) bool {
+	if containsString(b.ExceptNames, name) {
+		return false
+	}
+	for _, prefix := range b.ExcludePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	if len(b.IncludePrefixes) == 0 && len(b.OnlyNames) == 0 {
+		return true
+	}
+	if containsString(b.OnlyNames, name) {
+		return true
+	}
+	for _, prefix := range b.IncludePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkipExisting causes Bind to skip any flag that is already defined in the
+// FlagSet, such as by an earlier call to Bind with an overlapping struct,
+// instead of returning an error. The field is set to the value of the existing
+// flag, and again each time the flag is set, so that both structs observe the
+// flag. Bind returns ErrorExistingFlag if the existing flag does not have the
+// same type that the field would be bound as. Flags with the same name within
+// the same call to Bind still return ErrorDuplicateFlag.
+func SkipExisting() Option {
+	return func(b *bind) {
+		b.SkipExisting = true
+	}
+}
+
+// OverrideExisting causes Bind to take over any flag that is already defined
+// in the FlagSet, such as by a library, instead of returning an error. The
+// Value and default of the existing flag are replaced by those of the field,
+// and its usage is replaced if the field's Flag Tag sets one. Any short name
+// is kept. SkipExisting takes precedence.
+func OverrideExisting() Option {
+	return func(b *bind) {
+		b.OverrideExisting = true
+	}
+}
+
+// RenameCollisions causes Bind to rename a flag that would collide with a flag
+// that is already bound or defined, by prefixing it with the struct field
+// path of the struct that contains its field, instead of returning an error.
+// For example, if a flattened Client struct has a Timeout field that collides
+// with --timeout, it is bound as --client-timeout, without any short name.
+// Each Rename is passed to report, if it is not nil. Bind still returns
+// ErrorDuplicateFlag if the field is not nested, or the new name also
+// collides.
+func RenameCollisions(report func(Rename)) Option {
+	return func(b *bind) {
+		b.OnRename = report
+		if b.OnRename == nil {
+			b.OnRename = func(Rename) {}
+		}
+	}
+}
+
+// RequireTags causes Bind to return ErrorMissingTag for any field without a
+// `flag` tag that would define a flag, so that the name and usage of every
+// flag is an explicit decision. Nested structs, and maps and slices of
+// structs, do not require a tag, but their fields do.
+func RequireTags() Option {
+	return func(b *bind) {
+		b.RequireTags = true
+	}
+}
+
+// IgnoreUntagged causes Bind to skip any field without a `flag` tag that would
+// define a flag, so that existing structs may be bound without marking every
+// other field with `flag:"-"`. Nested structs, and maps and slices of structs,
+// are still searched for tagged fields. This takes precedence over
+// RequireTags.
+func IgnoreUntagged() Option {
+	return func(b *bind) {
+		b.IgnoreUntagged = true
+	}
+}
+
+// DefaultsFrom causes Bind to look up the default of each flag in src, using
+// the flag name, or the `key` tag option, as the key. A value that is found
+// replaces both the Flag Tag <default> and the struct field value, and has
+// OriginConfig. This allows remote key-value stores, such as etcd or Consul,
+// to provide defaults. If src is a ValueSourceContext, it is called with the
+// ctx passed to BindContext. Use SourceLayer to consult src after fs.Parse
+// instead.
+func DefaultsFrom(src ValueSource) Option {
+	return func(b *bind) {
+		b.ValueSource = src
+	}
+}
+
+// Synchronized causes Bind to guard each flag that it binds to a struct field
+// with a lock held by vals, which provides typed getters by flag name, so that
+// values updated by Resolve or Watch may be safely read from other goroutines.
+// For example:
+//
+//      var vals flagbind.Values
+//      err := flagbind.Bind(fs, &flags, flagbind.Synchronized(&vals))
+//      ...
+//      go flagbind.Watch(ctx, src, fs, nil)
+//      ...
+//      timeout := vals.Duration("timeout")
+//
+// Flags defined by a Binder are not synchronized.
+func Synchronized(vals *Values) Option {
+	return func(b *bind) {
+		b.Values = vals
+	}
+}
+
+// The ErrorHandling policies that may be passed to OnError.
+const (
+	ContinueOnError = flag.ContinueOnError
+	ExitOnError     = flag.ExitOnError
+	PanicOnError    = flag.PanicOnError
+)
+
+// OnError sets how Bind handles an error, like flag.ErrorHandling. With
+// ContinueOnError, the default, Bind returns the error. With ExitOnError, Bind
+// prints the error to the Output of fs, if it is a *flag.FlagSet, or else
+// os.Stderr, and exits with status 2. With PanicOnError, Bind panics with the
+// error.
+func OnError(h flag.ErrorHandling) Option {
+	return func(b *bind) {
+		b.ErrorHandling = h
+	}
+}
+
+// exit is os.Exit, and may be replaced by tests.
+var exit = os.Exit
+
+// handleError applies the ErrorHandling to the non-nil err from Bind.
+func (b bind) handleError(fs FlagSet, err error) error {
+	switch b.ErrorHandling {
+	case ExitOnError:
+		var out io.Writer = os.Stderr
+		if fs, ok := fs.(interface{ Output() io.Writer }); ok {
+			out = fs.Output()
+		}
+		fmt.Fprintln(out, err)
+		exit(2)
+	case PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+// StrictShortNames causes Bind to return ErrorShortName instead of silently
+// ignoring a short name that is longer than a single character, or that is
+// ignored because `fs` does not implement PFlagSet.
+func StrictShortNames() Option {
+	return func(b *bind) {
+		b.StrictShortNames = true
+	}
+}
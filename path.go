@@ -0,0 +1,163 @@
+package flagbind
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+)
+
+// File is a file path. By default any path is accepted. The following tag
+// <options> check the file when the flag is set.
+//
+//	exists - The file must exist and must not be a directory.
+//
+//	readable - The file must exist and be readable.
+//
+//	create - Create the file if it does not exist.
+//
+//	not-exists - The file must not exist.
+type File string
+
+func (f *File) Set(text string) error {
+	*f = File(text)
+	return nil
+}
+
+func (f File) String() string { return string(f) }
+
+func (f File) Type() string { return "file" }
+
+// checkedFile is a File that is checked when it is Set.
+type checkedFile struct {
+	*File
+	tag flagTag
+}
+
+func newFileValue(f *File, tag flagTag) pflag.Value {
+	if tag.Unchecked ||
+		!(tag.Exists || tag.Readable || tag.Create || tag.NotExists) {
+		return f
+	}
+	return checkedFile{f, tag}
+}
+
+func (f checkedFile) String() string {
+	if f.File == nil {
+		return ""
+	}
+	return f.File.String()
+}
+
+func (f checkedFile) Set(path string) error {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		if f.tag.Create {
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0666)
+			if err != nil {
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+		} else if f.tag.Exists || f.tag.Readable {
+			return fmt.Errorf("file %q does not exist", path)
+		}
+	case err != nil:
+		return err
+	case f.tag.NotExists:
+		return fmt.Errorf("file %q already exists", path)
+	case info.IsDir():
+		return fmt.Errorf("%q is a directory", path)
+	case f.tag.Readable:
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return f.File.Set(path)
+}
+
+// Dir is a directory path. When set, the path is cleaned and made absolute,
+// and it must be an existing directory. The `create` tag option creates the
+// directory, including any parents, if it does not exist.
+type Dir string
+
+func (d *Dir) Set(text string) error {
+	path, err := filepath.Abs(text)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("directory %q does not exist", path)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", path)
+	}
+	*d = Dir(path)
+	return nil
+}
+
+func (d Dir) String() string { return string(d) }
+
+func (d Dir) Type() string { return "dir" }
+
+// createDir is a Dir that is created if it does not exist when it is Set.
+type createDir struct {
+	*Dir
+}
+
+func newDirValue(d *Dir, tag flagTag) pflag.Value {
+	if tag.Unchecked {
+		return uncheckedDir{d}
+	}
+	if !tag.Create {
+		return d
+	}
+	return createDir{d}
+}
+
+func (d createDir) String() string {
+	if d.Dir == nil {
+		return ""
+	}
+	return d.Dir.String()
+}
+
+func (d createDir) Set(text string) error {
+	if err := os.MkdirAll(text, 0777); err != nil {
+		return err
+	}
+	return d.Dir.Set(text)
+}
+
+// uncheckedDir is a Dir that is only cleaned and made absolute when it is Set,
+// so that Inspect neither requires nor creates the directory.
+type uncheckedDir struct {
+	*Dir
+}
+
+func (d uncheckedDir) String() string {
+	if d.Dir == nil {
+		return ""
+	}
+	return d.Dir.String()
+}
+
+func (d uncheckedDir) Set(text string) error {
+	path, err := filepath.Abs(text)
+	if err != nil {
+		return err
+	}
+	*d.Dir = Dir(path)
+	return nil
+}
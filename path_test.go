@@ -0,0 +1,107 @@
+package flagbind
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flagbind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing")
+	require.NoError(t, ioutil.WriteFile(existing, nil, 0600))
+	missing := filepath.Join(dir, "missing")
+	created := filepath.Join(dir, "created")
+
+	var f struct {
+		Any       File
+		Exists    File `flag:";;;exists"`
+		Readable  File `flag:";;;readable"`
+		Create    File `flag:";;;create"`
+		NotExists File `flag:";;;not-exists"`
+	}
+	bind := func() *pflag.FlagSet {
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		require.NoError(t, Bind(fs, &f))
+		return fs
+	}
+
+	fs := bind()
+	assert.Equal(t, "file", fs.Lookup("any").Value.Type())
+	require.NoError(t, fs.Parse([]string{
+		"--any", missing,
+		"--exists", existing,
+		"--readable", existing,
+		"--create", created,
+		"--not-exists", missing,
+	}))
+	assert.Equal(t, File(missing), f.Any)
+	assert.Equal(t, File(existing), f.Exists)
+	assert.Equal(t, File(existing), f.Readable)
+	assert.Equal(t, File(created), f.Create)
+	assert.Equal(t, File(missing), f.NotExists)
+	assert.FileExists(t, created)
+
+	for _, args := range [][]string{
+		{"--exists", missing},
+		{"--exists", dir},
+		{"--readable", missing},
+		{"--not-exists", existing},
+	} {
+		assert.Error(t, bind().Parse(args), args)
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flagbind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	missing := filepath.Join(dir, "missing")
+	created := filepath.Join(dir, "created", "nested")
+
+	var f struct {
+		Dir    Dir
+		Create Dir `flag:";;;create"`
+	}
+	bind := func() *pflag.FlagSet {
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		require.NoError(t, Bind(fs, &f))
+		return fs
+	}
+
+	fs := bind()
+	assert.Equal(t, "dir", fs.Lookup("dir").Value.Type())
+	require.NoError(t, fs.Parse([]string{
+		"--dir", dir + "/./created/..",
+		"--create", created,
+	}))
+	assert.Equal(t, Dir(dir), f.Dir)
+	assert.Equal(t, Dir(created), f.Create)
+	assert.DirExists(t, created)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, bind().Parse([]string{"--dir", "."}))
+	assert.Equal(t, Dir(wd), f.Dir)
+
+	for _, args := range [][]string{
+		{"--dir", missing},
+		{"--dir", file},
+		{"--create", file},
+	} {
+		assert.Error(t, bind().Parse(args), args)
+	}
+}
@@ -0,0 +1,38 @@
+package flagbind
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Percent is a fraction between 0 and 1, inclusive. It is a flag.Value that
+// accepts either a percentage, such as "85%", or a fraction, such as "0.85".
+// String always prints a percentage.
+type Percent float64
+
+func (p *Percent) Set(text string) error {
+	num := strings.TrimSpace(text)
+	isPercent := strings.HasSuffix(num, "%")
+	num = strings.TrimSpace(strings.TrimSuffix(num, "%"))
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("invalid percent %q", text)
+	}
+	if isPercent {
+		f /= 100
+	}
+	if f < 0 || f > 1 {
+		return fmt.Errorf("percent %q is not within 0%% and 100%%", text)
+	}
+	*p = Percent(f)
+	return nil
+}
+
+func (p Percent) String() string {
+	return fmt.Sprintf("%.10g%%", float64(p)*100)
+}
+
+func (p Percent) Type() string { return "percent" }
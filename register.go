@@ -0,0 +1,197 @@
+package flagbind
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// TypeHandler returns a flag.Value for `ptr`, which is a pointer to a field,
+// and true if it handles the field's type. Otherwise it returns false.
+type TypeHandler func(ptr interface{}) (flag.Value, bool)
+
+var typeHandlers struct {
+	sync.RWMutex
+	handlers []TypeHandler
+}
+
+// RegisterType registers a TypeHandler that teaches Bind how to bind types it
+// does not otherwise support, such as types from third party packages.
+//
+// Registered handlers are consulted for every field before any other rules,
+// including Binder and nested struct handling, with the most recently
+// registered handler consulted first. If a handler returns true, the field is
+// bound as the returned flag.Value.
+//
+// RegisterType is safe for concurrent use, but is typically called from an
+// init function.
+func RegisterType(handler TypeHandler) {
+	typeHandlers.Lock()
+	defer typeHandlers.Unlock()
+	typeHandlers.handlers = append(typeHandlers.handlers, handler)
+}
+
+// registeredValue returns the flag.Value from the most recently registered
+// TypeHandler that handles ptr.
+func registeredValue(ptr interface{}) (flag.Value, bool) {
+	typeHandlers.RLock()
+	defer typeHandlers.RUnlock()
+	for i := len(typeHandlers.handlers) - 1; i >= 0; i-- {
+		if val, ok := typeHandlers.handlers[i](ptr); ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+var normalizers = struct {
+	sync.RWMutex
+	funcs map[string]func(string) string
+}{funcs: map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}}
+
+// RegisterNormalizer registers a func by name for use with the
+// `normalize=<name>[|<name>...]` tag option, which applies each named func to
+// the text of a flag before it is Set. The "trim", "lower", and "upper"
+// normalizers, which use strings.TrimSpace, strings.ToLower, and
+// strings.ToUpper, are registered by default, and may be replaced.
+//
+// RegisterNormalizer is safe for concurrent use, but is typically called from
+// an init function.
+func RegisterNormalizer(name string, normalize func(string) string) {
+	normalizers.Lock()
+	defer normalizers.Unlock()
+	normalizers.funcs[name] = normalize
+}
+
+// normalizer returns a transform that applies the registered normalizers with
+// the given names, in order.
+func normalizer(names []string) (func(string) (string, error), error) {
+	normalizers.RLock()
+	defer normalizers.RUnlock()
+	funcs := make([]func(string) string, len(names))
+	for i, name := range names {
+		fn, ok := normalizers.funcs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown normalizer: %q", name)
+		}
+		funcs[i] = fn
+	}
+	return func(text string) (string, error) {
+		for _, fn := range funcs {
+			text = fn(text)
+		}
+		return text, nil
+	}, nil
+}
+
+var defaultFuncs = struct {
+	sync.RWMutex
+	funcs map[string]func() string
+}{funcs: make(map[string]func() string)}
+
+// RegisterDefaultFunc registers a func by name for use with the
+// `default-func=<name>` tag option, which calls the func at bind time to
+// compute the <default> of a flag, such as the number of CPUs or the hostname.
+//
+// RegisterDefaultFunc is safe for concurrent use, but is typically called
+// from an init function.
+func RegisterDefaultFunc(name string, fn func() string) {
+	defaultFuncs.Lock()
+	defer defaultFuncs.Unlock()
+	defaultFuncs.funcs[name] = fn
+}
+
+// defaultFunc returns the registered default func with the given name.
+func defaultFunc(name string) (func() string, error) {
+	defaultFuncs.RLock()
+	defer defaultFuncs.RUnlock()
+	fn, ok := defaultFuncs.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown default func: %q", name)
+	}
+	return fn, nil
+}
+
+var secretResolvers = struct {
+	sync.RWMutex
+	funcs map[string]func(ctx context.Context, ref string) (string, error)
+}{funcs: make(
+	map[string]func(ctx context.Context, ref string) (string, error))}
+
+// RegisterSecretResolver registers a func for the scheme used by the
+// `secret=<scheme>:<ref>` tag option, such as "aws-ssm" in
+// `secret=aws-ssm:/app/db-password`. The func is called with the <ref> to
+// fetch the secret. See SecretLayer.
+//
+// RegisterSecretResolver is safe for concurrent use, but is typically called
+// from an init function.
+func RegisterSecretResolver(scheme string, resolve func(ref string) (string, error)) {
+	RegisterSecretResolverContext(scheme,
+		func(_ context.Context, ref string) (string, error) {
+			return resolve(ref)
+		})
+}
+
+// RegisterSecretResolverContext is like RegisterSecretResolver, but the func
+// is also called with the ctx passed to ResolveContext, or
+// context.Background() if the secret is resolved by Resolve.
+func RegisterSecretResolverContext(scheme string,
+	resolve func(ctx context.Context, ref string) (string, error)) {
+	secretResolvers.Lock()
+	defer secretResolvers.Unlock()
+	secretResolvers.funcs[scheme] = resolve
+}
+
+// secretResolver returns the registered secret resolver for the scheme of
+// the secret reference `<scheme>:<ref>`, and the <ref>.
+func secretResolver(secret string) (
+	func(context.Context, string) (string, error), string, error) {
+	i := strings.Index(secret, ":")
+	if i < 0 {
+		return nil, "", fmt.Errorf("missing secret resolver scheme")
+	}
+	scheme, ref := secret[:i], secret[i+1:]
+	secretResolvers.RLock()
+	defer secretResolvers.RUnlock()
+	fn, ok := secretResolvers.funcs[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown secret resolver: %q", scheme)
+	}
+	return fn, ref, nil
+}
+
+var onChangeFuncs = struct {
+	sync.RWMutex
+	funcs map[string]func(name, value string)
+}{funcs: make(map[string]func(name, value string))}
+
+// RegisterOnChange registers a func by name for use with the
+// `onchange=<name>` tag option, which calls the func with the flag name and
+// the String of its value each time the flag is Set, including by Resolve
+// and Watch, such as to apply a new log level immediately. It is not called
+// for the <default>.
+//
+// RegisterOnChange is safe for concurrent use, but is typically called from
+// an init function.
+func RegisterOnChange(name string, fn func(name, value string)) {
+	onChangeFuncs.Lock()
+	defer onChangeFuncs.Unlock()
+	onChangeFuncs.funcs[name] = fn
+}
+
+// onChangeFunc returns the registered onchange func with the given name.
+func onChangeFunc(name string) (func(name, value string), error) {
+	onChangeFuncs.RLock()
+	defer onChangeFuncs.RUnlock()
+	fn, ok := onChangeFuncs.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown onchange func: %q", name)
+	}
+	return fn, nil
+}
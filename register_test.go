@@ -0,0 +1,145 @@
+package flagbind
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RegisteredType would otherwise be bound as a nested struct.
+type RegisteredType struct {
+	Parts []string
+}
+
+type registeredTypeValue struct {
+	*RegisteredType
+}
+
+func (v registeredTypeValue) Set(text string) error {
+	v.Parts = strings.Split(text, ":")
+	return nil
+}
+
+func (v registeredTypeValue) String() string {
+	if v.RegisteredType == nil {
+		return ""
+	}
+	return strings.Join(v.Parts, ":")
+}
+
+func init() {
+	RegisterType(func(ptr interface{}) (flag.Value, bool) {
+		if p, ok := ptr.(*RegisteredType); ok {
+			return registeredTypeValue{p}, true
+		}
+		return nil, false
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	var f struct {
+		Reg RegisteredType `flag:";a:b"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, []string{"a", "b"}, f.Reg.Parts)
+	assert.Equal(t, "RegisteredType", fs.Lookup("reg").Value.Type())
+
+	require.NoError(t, fs.Parse([]string{"--reg", "c:d:e"}))
+	assert.Equal(t, []string{"c", "d", "e"}, f.Reg.Parts)
+}
+
+func TestRegisterNormalizer(t *testing.T) {
+	RegisterNormalizer("no-dashes", func(text string) string {
+		return strings.ReplaceAll(text, "-", "")
+	})
+
+	var f struct {
+		Format string   `flag:";;;normalize=trim|lower,choices=json|yaml"`
+		Code   string   `flag:";;;normalize=upper|no-dashes"`
+		Tags   []string `flag:";;;normalize=trim"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{
+		"--format", " JSON ", "--code", "ab-c", "--tags", " a,b "}))
+	assert.Equal(t, "json", f.Format)
+	assert.Equal(t, "ABC", f.Code)
+	assert.Equal(t, []string{"a", "b"}, f.Tags)
+
+	var g struct {
+		Value string `flag:";;;normalize=missing"`
+	}
+	err := Bind(flag.NewFlagSet("", flag.ContinueOnError), &g)
+	assert.EqualError(t, err, `unknown normalizer: "missing"`)
+}
+
+type defaulterPort int
+
+func (defaulterPort) DefaultFlagValue() string { return "8080" }
+
+func (p *defaulterPort) Set(text string) error {
+	_, err := fmt.Sscan(text, (*int)(p))
+	return err
+}
+
+func (p *defaulterPort) String() string { return fmt.Sprint(int(*p)) }
+
+func TestDefaultFunc(t *testing.T) {
+	RegisterDefaultFunc("test-host", func() string { return "example.com" })
+
+	var f struct {
+		Host  string        `flag:";;;default-func=test-host"`
+		Port  defaulterPort `flag:";;Port"`
+		Fixed defaulterPort `flag:";80"`
+		Set   defaulterPort
+	}
+	f.Set = 1
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Equal(t, "example.com", f.Host)
+	assert.Equal(t, defaulterPort(8080), f.Port)
+	assert.Equal(t, defaulterPort(80), f.Fixed)
+	assert.Equal(t, defaulterPort(1), f.Set)
+	assert.Equal(t, "8080", fs.Lookup("port").DefValue)
+
+	var g struct {
+		Host string `flag:";;;default-func=missing"`
+	}
+	err := Bind(flag.NewFlagSet("", flag.ContinueOnError), &g)
+	assert.EqualError(t, err, `unknown default func: "missing"`)
+}
+
+func TestOnChange(t *testing.T) {
+	var changes []string
+	RegisterOnChange("test-change", func(name, value string) {
+		changes = append(changes, name+"="+value)
+	})
+
+	var f struct {
+		Level   string `flag:";info;;onchange=test-change"`
+		Verbose bool   `flag:";;;onchange=test-change,skip-zero-default"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	assert.Empty(t, changes, "not called for the default")
+
+	require.NoError(t, fs.Parse([]string{"--level", "debug", "--verbose"}))
+	require.NoError(t, Resolve(fs, ConfigLayer(map[string]string{
+		"level": "warn"})))
+	require.NoError(t, SetFrom(fs, "level", "", OriginConfig))
+	assert.Equal(t, []string{"level=debug", "verbose=true", "level="},
+		changes)
+	assert.True(t, f.Verbose)
+
+	var g struct {
+		Level string `flag:";;;onchange=missing"`
+	}
+	err := Bind(pflag.NewFlagSet("", pflag.ContinueOnError), &g)
+	assert.EqualError(t, err, `unknown onchange func: "missing"`)
+}
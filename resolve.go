@@ -0,0 +1,405 @@
+package flagbind
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// Layer is a source of flag values with an Origin, such as environment
+// variables or a config file. See Resolve.
+type Layer struct {
+	Origin Origin
+
+	// Lookup returns the value for the flag name, and whether it has
+	// one.
+	Lookup func(name string) (value string, ok bool, err error)
+
+	// LookupContext, if not nil, is called by ResolveContext with its ctx
+	// instead of Lookup.
+	LookupContext func(ctx context.Context, name string) (
+		value string, ok bool, err error)
+}
+
+// lookup calls LookupContext with ctx if it is set, or else Lookup.
+func (l Layer) lookup(ctx context.Context, name string) (string, bool, error) {
+	if l.LookupContext != nil {
+		return l.LookupContext(ctx, name)
+	}
+	return l.Lookup(name)
+}
+
+// ValueSource is a source of flag values, such as a remote key-value store.
+// Lookup returns the value for key and whether it has one. See DefaultsFrom
+// and SourceLayer.
+type ValueSource interface {
+	Lookup(key string) (value string, ok bool, err error)
+}
+
+// ValueSourceFunc adapts a func to the ValueSource interface.
+type ValueSourceFunc func(key string) (string, bool, error)
+
+// Lookup calls fn(key).
+func (fn ValueSourceFunc) Lookup(key string) (string, bool, error) {
+	return fn(key)
+}
+
+// ValueSourceContext is a ValueSource that accepts a context.Context, such as
+// to cancel a remote lookup. BindContext and ResolveContext call LookupContext
+// with their ctx instead of Lookup.
+type ValueSourceContext interface {
+	ValueSource
+	LookupContext(ctx context.Context, key string) (
+		value string, ok bool, err error)
+}
+
+// ValueSourceContextFunc adapts a func to the ValueSourceContext interface.
+type ValueSourceContextFunc func(ctx context.Context, key string) (
+	string, bool, error)
+
+// Lookup calls fn(context.Background(), key).
+func (fn ValueSourceContextFunc) Lookup(key string) (string, bool, error) {
+	return fn(context.Background(), key)
+}
+
+// LookupContext calls fn(ctx, key).
+func (fn ValueSourceContextFunc) LookupContext(ctx context.Context,
+	key string) (string, bool, error) {
+	return fn(ctx, key)
+}
+
+// lookupSource looks up key in src, with ctx if src is a ValueSourceContext.
+func lookupSource(ctx context.Context, src ValueSource,
+	key string) (string, bool, error) {
+	if src, ok := src.(ValueSourceContext); ok {
+		return src.LookupContext(ctx, key)
+	}
+	return src.Lookup(key)
+}
+
+// SourceLayer returns a Layer with the given Origin that looks up each flag
+// name in src, with the ctx passed to ResolveContext if src is a
+// ValueSourceContext.
+func SourceLayer(origin Origin, src ValueSource) Layer {
+	layer := Layer{Origin: origin, Lookup: src.Lookup}
+	if src, ok := src.(ValueSourceContext); ok {
+		layer.LookupContext = src.LookupContext
+	}
+	return layer
+}
+
+// EnvLayer returns a Layer with OriginEnv that looks up the environment
+// variable for each flag named by EnvName.
+func EnvLayer(prefix string) Layer {
+	lookup := func(name string) (string, bool, error) {
+		value, ok := os.LookupEnv(EnvName(prefix, name))
+		return value, ok, nil
+	}
+	return Layer{Origin: OriginEnv, Lookup: lookup}
+}
+
+// EnvName returns the environment variable name for the flag name, which is
+// the prefix followed by the name in upper case with any character that is not
+// a letter or digit replaced by "_". For example, EnvName("APP_",
+// "http-timeout") returns "APP_HTTP_TIMEOUT".
+func EnvName(prefix, name string) string {
+	return prefix + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// SecretLayer returns a Layer with OriginSecret that resolves the
+// `secret=<scheme>:<ref>` tag option of each flag that Bind defines for v,
+// with the given opts, using the secret resolver registered for the scheme
+// with RegisterSecretResolver or RegisterSecretResolverContext. Secrets are
+// only resolved when Resolve looks them up, after fs.Parse, so they never need
+// to be passed in argv or the environment. ResolveContext passes its ctx to
+// the secret resolver.
+func SecretLayer(v interface{}, opts ...Option) (Layer, error) {
+	infos, err := Inspect(v, opts...)
+	if err != nil {
+		return Layer{}, err
+	}
+	secrets := make(map[string]string)
+	for _, info := range infos {
+		if secret, _ := info.Option("secret"); secret != "" {
+			secrets[info.Name] = secret
+		}
+	}
+	lookup := func(ctx context.Context, name string) (string, bool, error) {
+		secret, ok := secrets[name]
+		if !ok {
+			return "", false, nil
+		}
+		resolve, ref, err := secretResolver(secret)
+		if err == nil {
+			var value string
+			if value, err = resolve(ctx, ref); err == nil {
+				return value, true, nil
+			}
+		}
+		return "", false, ErrorSecret{secret, err}
+	}
+	return Layer{
+		Origin: OriginSecret,
+		Lookup: func(name string) (string, bool, error) {
+			return lookup(context.Background(), name)
+		},
+		LookupContext: lookup,
+	}, nil
+}
+
+// ConfigLayer returns a Layer with OriginConfig that looks up each flag name in
+// config, such as from ReadJSONConfig.
+func ConfigLayer(config map[string]string) Layer {
+	lookup := func(name string) (string, bool, error) {
+		value, ok := config[name]
+		return value, ok, nil
+	}
+	return Layer{Origin: OriginConfig, Lookup: lookup}
+}
+
+// ReadJSONConfig reads a JSON object from r and returns the value for each
+// flag name, for use with ConfigLayer. The keys of nested objects are joined
+// to their parent key with Separator, so {"http": {"timeout": "5s"}} sets
+// "http-timeout". Strings are used as is, arrays of strings, numbers and bools
+// are joined with commas, and anything else is used as JSON.
+func ReadJSONConfig(r io.Reader) (map[string]string, error) {
+	var obj map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, err
+	}
+	config := make(map[string]string)
+	flattenJSON(config, "", obj)
+	return config, nil
+}
+
+// flattenJSON adds each value in obj to config under prefix followed by its
+// key.
+func flattenJSON(config map[string]string, prefix string,
+	obj map[string]interface{}) {
+	for key, val := range obj {
+		key = prefix + key
+		if obj, ok := val.(map[string]interface{}); ok {
+			flattenJSON(config, key+Separator, obj)
+			continue
+		}
+		config[key] = jsonText(val)
+	}
+}
+
+// jsonText returns the flag value text for a decoded JSON value.
+func jsonText(val interface{}) string {
+	switch val := val.(type) {
+	case string:
+		return val
+	case float64, bool:
+		return fmt.Sprint(val)
+	case []interface{}:
+		elems := make([]string, len(val))
+		for i, elem := range val {
+			switch elem.(type) {
+			case string, float64, bool:
+				elems[i] = fmt.Sprint(elem)
+			default:
+				data, _ := json.Marshal(val)
+				return string(data)
+			}
+		}
+		return strings.Join(elems, ",")
+	}
+	data, _ := json.Marshal(val)
+	return string(data)
+}
+
+// Resolve sets each flag in fs that was not set on the command line from the
+// layer with the highest Origin that has a value for it, using SetFrom, so
+// that Source reports where each value came from. Call Resolve after
+// fs.Parse. The precedence is the command line, then environment variables,
+// then config files, then the struct field value, then the Flag Tag
+// <default>. For example:
+//
+//      config, err := flagbind.ReadJSONConfig(file)
+//      ...
+//      err = flagbind.Resolve(fs, flagbind.EnvLayer("APP_"),
+//              flagbind.ConfigLayer(config))
+//
+// Layers with the same Origin take precedence in the order given.
+//
+// Resolve may be called again to reload the layers. Each flag that it set
+// before is first reset to the value it had before, so that slices and maps
+// are not accumulated, and a flag that no longer has a value in any layer
+// returns to its struct field value or Flag Tag <default>.
+//
+// The `precedence` Flag Tag option restricts and reorders the Origins that a
+// flag may be set from. For example, `precedence=env|secret` sets the flag from
+// an environment variable, or else a secret, but never from a config file,
+// and Resolve returns an error if it was set on the command line. If "flag",
+// "value", or "default" is listed, a layer listed after it does not override
+// the command line, the struct field value, or the Flag Tag <default>.
+//
+// If a layer cannot look up or set a flag, Resolve returns ErrorResolve.
+func Resolve(fs FlagSet, layers ...Layer) error {
+	return ResolveContext(context.Background(), fs, layers...)
+}
+
+// ResolveContext is like Resolve, but passes ctx to the LookupContext of each
+// Layer that has one, such as from SourceLayer with a ValueSourceContext, or
+// from SecretLayer. ResolveContext returns ctx.Err() if ctx is done before
+// every flag is resolved.
+func ResolveContext(ctx context.Context, fs FlagSet, layers ...Layer) error {
+	sorted := make([]Layer, len(layers))
+	copy(sorted, layers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Origin > sorted[j].Origin
+	})
+
+	set := setFlags(fs)
+	for _, name := range flagNames(fs) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		v := flagValue(fs, name)
+		if _, ok := aliasOf(v); ok {
+			continue
+		}
+		ov := findOrigin(v)
+		var precedence []Origin
+		if ov != nil {
+			precedence = ov.precedence
+		}
+		// The base Origin is where the value came from before any
+		// layer set it.
+		base := source(fs, name, set)
+		if base != OriginFlag && ov != nil && ov.reset != nil {
+			base = ov.base
+		}
+		if base == OriginFlag {
+			if precedence == nil {
+				continue
+			}
+			if rank(precedence, OriginFlag) == len(precedence) {
+				return fmt.Errorf(
+					"flag %q: may not be set on the command line",
+					name)
+			}
+		}
+
+		layers := sorted
+		if precedence != nil {
+			layers = orderLayers(sorted, precedence)
+		}
+		var value string
+		var layer Layer
+		var ok bool
+		for _, layer = range layers {
+			if precedence != nil &&
+				rank(precedence, layer.Origin) >=
+					rank(precedence, base) {
+				break
+			}
+			var err error
+			value, ok, err = layer.lookup(ctx, name)
+			if err != nil {
+				return ErrorResolve{name, layer.Origin, err}
+			}
+			if ok {
+				break
+			}
+		}
+		// A command line value that is overridden must be saved so
+		// that it is restored if the layer no longer has a value.
+		if ok && base == OriginFlag {
+			ov = saveFlag(fs, name, set)
+		}
+		if ov != nil && ov.reset != nil {
+			if err := ov.reset(ok); err != nil {
+				return fmt.Errorf("flag %q: %w", name, err)
+			}
+		}
+		if !ok {
+			continue
+		}
+		err := setFrom(fs, name, value, layer.Origin, set)
+		if err != nil {
+			return ErrorResolve{name, layer.Origin, err}
+		}
+	}
+	return nil
+}
+
+// orderLayers returns the layers with an Origin in precedence, in that order.
+// Layers with the same Origin keep their order.
+func orderLayers(layers []Layer, precedence []Origin) []Layer {
+	var ordered []Layer
+	for _, origin := range precedence {
+		for _, layer := range layers {
+			if layer.Origin == origin {
+				ordered = append(ordered, layer)
+			}
+		}
+	}
+	return ordered
+}
+
+// rank returns the index of origin in precedence, or len(precedence) if it is
+// not listed.
+func rank(precedence []Origin, origin Origin) int {
+	for i, o := range precedence {
+		if o == origin {
+			return i
+		}
+	}
+	return len(precedence)
+}
+
+// Setting is the effective value of a flag and its Origin.
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Origin Origin `json:"origin"`
+}
+
+// String returns the Setting as `<name>=<value> (<origin>)`.
+func (s Setting) String() string {
+	return fmt.Sprintf("%v=%v (%v)", s.Name, s.Value, s.Origin)
+}
+
+// Effective returns the Setting of each flag in fs, sorted by name, so that
+// the effective configuration may be dumped with the Origin of each value.
+// Values are displayed as by PrintDefaults, so Secrets and `sensitive` flags
+// are redacted.
+func Effective(fs FlagSet) []Setting {
+	var settings []Setting
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		fs.VisitAll(func(f *flag.Flag) {
+			settings = append(settings,
+				Setting{f.Name, f.Value.String(), OriginDefault})
+		})
+	case PFlagSet:
+		fs.VisitAll(func(f *pflag.Flag) {
+			settings = append(settings,
+				Setting{f.Name, f.Value.String(), OriginDefault})
+		})
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Name < settings[j].Name
+	})
+	set := setFlags(fs)
+	for i := range settings {
+		settings[i].Origin = source(fs, settings[i].Name, set)
+	}
+	return settings
+}
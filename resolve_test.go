@@ -0,0 +1,303 @@
+package flagbind
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	var f struct {
+		Host  string `flag:";localhost"`
+		Port  int    `flag:";80"`
+		Level string `flag:";info"`
+		Tags  []string
+		User  string `flag:";;;secret"`
+		HTTP  struct {
+			Timeout time.Duration
+		}
+		Retries int
+	}
+	f.Retries = 3
+
+	config, err := ReadJSONConfig(strings.NewReader(`{
+		"host": "config.example.com",
+		"port": 8080,
+		"level": "warn",
+		"tags": ["a", "b"],
+		"http": {"timeout": "5s"}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"host":         "config.example.com",
+		"port":         "8080",
+		"level":        "warn",
+		"tags":         "a,b",
+		"http-timeout": "5s",
+	}, config)
+
+	os.Setenv("RESOLVE_TEST_PORT", "9090")
+	os.Setenv("RESOLVE_TEST_USER", "admin")
+	defer os.Unsetenv("RESOLVE_TEST_PORT")
+	defer os.Unsetenv("RESOLVE_TEST_USER")
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--level", "debug"}))
+	require.NoError(t, Resolve(fs, ConfigLayer(config),
+		EnvLayer("RESOLVE_TEST_")))
+
+	assert.Equal(t, "config.example.com", f.Host)
+	assert.Equal(t, 9090, f.Port)
+	assert.Equal(t, "debug", f.Level)
+	assert.Equal(t, []string{"a", "b"}, f.Tags)
+	assert.Equal(t, 5*time.Second, f.HTTP.Timeout)
+
+	assert.Equal(t, []Setting{
+		{"host", "config.example.com", OriginConfig},
+		{"http-timeout", "5s", OriginConfig},
+		{"level", "debug", OriginFlag},
+		{"port", "9090", OriginEnv},
+		{"retries", "3", OriginValue},
+		{"tags", "[a,b]", OriginConfig},
+		{"user", "***", OriginEnv},
+	}, Effective(fs))
+	assert.Equal(t, "port=9090 (env)", Effective(fs)[3].String())
+
+	// Resolving again reloads the layers, but not the command line.
+	config["level"] = "error"
+	config["port"] = "1"
+	os.Unsetenv("RESOLVE_TEST_PORT")
+	require.NoError(t, Resolve(fs, ConfigLayer(config)))
+	assert.Equal(t, 1, f.Port)
+	assert.Equal(t, "debug", f.Level)
+
+	config["port"] = "x"
+	assert.EqualError(t, Resolve(fs, ConfigLayer(config)),
+		`flag "port" from config: invalid argument "x" for "--port" flag: `+
+			`strconv.ParseInt: parsing "x": invalid syntax`)
+
+	assert.Equal(t, "APP_HTTP_TIMEOUT", EnvName("APP_", "http.timeout"))
+}
+
+func TestResolveReload(t *testing.T) {
+	var f struct {
+		Tags    []string `flag:";x"`
+		Labels  map[string]string
+		Verbose int `flag:";;;count"`
+		Host    string
+		Port    int
+	}
+	f.Port = 80
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse(nil))
+
+	config := map[string]string{
+		"tags":    "a,b",
+		"labels":  "env=prod,team=core",
+		"verbose": "+1",
+		"host":    "example.com",
+		"port":    "8080",
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, Resolve(fs, ConfigLayer(config)))
+		assert.Equal(t, []string{"a", "b"}, f.Tags)
+		assert.Equal(t, map[string]string{"env": "prod", "team": "core"},
+			f.Labels)
+		assert.Equal(t, 1, f.Verbose)
+		assert.Equal(t, OriginConfig, Source(fs, "tags"))
+	}
+
+	// Flags that are no longer in any layer are reset.
+	config = map[string]string{"labels": "env=dev"}
+	require.NoError(t, Resolve(fs, ConfigLayer(config)))
+	assert.Equal(t, []string{"x"}, f.Tags)
+	assert.Equal(t, map[string]string{"env": "dev"}, f.Labels)
+	assert.Equal(t, 0, f.Verbose)
+	assert.Equal(t, "", f.Host)
+	assert.Equal(t, 80, f.Port)
+	assert.Equal(t, OriginDefault, Source(fs, "tags"))
+	assert.Equal(t, OriginValue, Source(fs, "port"))
+	assert.Equal(t, OriginConfig, Source(fs, "labels"))
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	var f struct {
+		Token string `flag:";;;precedence=env|secret"`
+		Level string `flag:";info;;precedence=flag|config|env"`
+		Mode  string `flag:";;;precedence=env|flag"`
+	}
+	env := map[string]string{"token": "env", "level": "debug",
+		"mode": "env"}
+	config := map[string]string{"token": "config", "level": "warn"}
+	layers := []Layer{ConfigLayer(config), SourceLayer(OriginEnv,
+		ValueSourceFunc(func(name string) (string, bool, error) {
+			value, ok := env[name]
+			return value, ok, nil
+		}))}
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--mode", "flag"}))
+
+	require.NoError(t, Resolve(fs, layers...))
+	assert.Equal(t, "env", f.Token)
+	assert.Equal(t, OriginEnv, Source(fs, "token"))
+	assert.Equal(t, "warn", f.Level)
+	assert.Equal(t, OriginConfig, Source(fs, "level"))
+	assert.Equal(t, "env", f.Mode)
+	assert.Equal(t, OriginEnv, Source(fs, "mode"))
+
+	// The command line value is restored once env no longer overrides
+	// it, and a config file may never set the token.
+	env = map[string]string{}
+	require.NoError(t, Resolve(fs, layers...))
+	assert.Equal(t, "", f.Token)
+	assert.Equal(t, OriginDefault, Source(fs, "token"))
+	assert.Equal(t, "flag", f.Mode)
+	assert.Equal(t, OriginFlag, Source(fs, "mode"))
+
+	// The token may not be set on the command line.
+	require.NoError(t, fs.Parse([]string{"--token", "argv"}))
+	assert.EqualError(t, Resolve(fs, layers...),
+		`flag "token": may not be set on the command line`)
+}
+
+func TestDefaultsFrom(t *testing.T) {
+	kv := map[string]string{
+		"host":        "kv.example.com",
+		"app/timeout": "5s",
+		"port":        "x",
+	}
+	src := ValueSourceFunc(func(key string) (string, bool, error) {
+		value, ok := kv[key]
+		return value, ok, nil
+	})
+
+	var f struct {
+		Host    string        `flag:";localhost"`
+		Timeout time.Duration `flag:";;;key=app/timeout"`
+		Level   string
+	}
+	f.Host = "value.example.com"
+	f.Level = "info"
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, DefaultsFrom(src)))
+	assert.Equal(t, "kv.example.com", f.Host)
+	assert.Equal(t, 5*time.Second, f.Timeout)
+	assert.Equal(t, "kv.example.com", fs.Lookup("host").DefValue)
+	assert.Equal(t, OriginConfig, Source(fs, "host"))
+	assert.Equal(t, OriginValue, Source(fs, "level"))
+
+	var g struct{ Port int }
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	assert.IsType(t, ErrorDefaultValue{}, Bind(fs, &g, DefaultsFrom(src)))
+
+	lookupErr := errors.New("unavailable")
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	err := Bind(fs, &g, DefaultsFrom(ValueSourceFunc(
+		func(string) (string, bool, error) { return "", false, lookupErr })))
+	assert.Equal(t, ErrorValueSource{"port", lookupErr}, err)
+	assert.EqualError(t, err, `cannot look up "port": unavailable`)
+
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &g))
+	require.NoError(t, Resolve(fs, SourceLayer(OriginConfig,
+		ValueSourceFunc(func(key string) (string, bool, error) {
+			return "8080", key == "port", nil
+		}))))
+	assert.Equal(t, 8080, g.Port)
+}
+
+func TestSecretLayer(t *testing.T) {
+	RegisterSecretResolver("test-secret", func(ref string) (string, error) {
+		if ref == "/missing" {
+			return "", errors.New("not found")
+		}
+		return "secret:" + ref, nil
+	})
+
+	var f struct {
+		Password string `flag:";;;secret=test-secret:/app/db"`
+		Token    string `flag:";;;secret=test-secret:/app/token"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--token", "cli"}))
+	layer, err := SecretLayer(&f)
+	require.NoError(t, err)
+	require.NoError(t, Resolve(fs, layer))
+	assert.Equal(t, "secret:/app/db", f.Password)
+	assert.Equal(t, "cli", f.Token)
+	assert.Equal(t, OriginSecret, Source(fs, "password"))
+	assert.Equal(t, "***", fs.Lookup("password").Value.String())
+
+	var g struct {
+		Password string `flag:";;;secret=test-secret:/missing"`
+	}
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &g))
+	layer, err = SecretLayer(&g)
+	require.NoError(t, err)
+	err = Resolve(fs, layer)
+	assert.True(t, errors.As(err, &ErrorSecret{}))
+	assert.EqualError(t, err, `flag "password" from secret: `+
+		`cannot resolve secret "test-secret:/missing": not found`)
+
+	var h struct {
+		Password string `flag:";;;secret=unknown:/app/db"`
+	}
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	assert.EqualError(t, Bind(fs, &h), `cannot resolve secret `+
+		`"unknown:/app/db": unknown secret resolver: "unknown"`)
+}
+
+func TestResolveContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "ctx")
+	src := ValueSourceContextFunc(func(ctx context.Context,
+		key string) (string, bool, error) {
+		value, _ := ctx.Value(ctxKey{}).(string)
+		return value, value != "", nil
+	})
+	RegisterSecretResolverContext("test-ctx-secret",
+		func(ctx context.Context, ref string) (string, error) {
+			value, _ := ctx.Value(ctxKey{}).(string)
+			return value + ref, nil
+		})
+
+	var f struct {
+		Host     string
+		Password string `flag:";;;secret=test-ctx-secret:/app/db"`
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, BindContext(ctx, fs, &f, DefaultsFrom(src)))
+	assert.Equal(t, "ctx", f.Host)
+
+	f.Host = ""
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, DefaultsFrom(src)))
+	assert.Equal(t, "", f.Host)
+
+	layer, err := SecretLayer(&f)
+	require.NoError(t, err)
+	require.NoError(t, ResolveContext(ctx, fs,
+		SourceLayer(OriginConfig, src), layer))
+	assert.Equal(t, "ctx", f.Host)
+	assert.Equal(t, "ctx/app/db", f.Password)
+
+	require.NoError(t, Resolve(fs, layer))
+	assert.Equal(t, "/app/db", f.Password)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, ResolveContext(canceled, fs, layer))
+}
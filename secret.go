@@ -0,0 +1,63 @@
+package flagbind
+
+// Secret is a string that is redacted when displayed. Its String method
+// returns "***" for any non-empty value so that secrets do not leak into the
+// usage, the DefValue of the flag, or anything that prints the FlagSet. Use
+// Reveal to access the actual value.
+//
+// A string field may also be bound as a Secret using the `secret` tag option.
+type Secret string
+
+const redacted = "***"
+
+func (s *Secret) Set(text string) error {
+	*s = Secret(text)
+	return nil
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) Type() string { return "secret" }
+
+// Reveal returns the actual value of the Secret.
+func (s Secret) Reveal() string { return string(s) }
+
+// sensitiveValue is a flag.Value that is redacted when displayed, like a
+// Secret, for the `sensitive` tag option.
+type sensitiveValue struct {
+	transformValue
+}
+
+func (v sensitiveValue) String() string {
+	if v.Value == nil || v.Value.String() == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactFlag wraps the Value of the flag name so that it is redacted when
+// displayed, and redacts its DefValue unless the field has its zero value.
+func redactFlag(fs FlagSet, name string, isZero bool) {
+	noop := func(text string) (string, error) { return text, nil }
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(
+			sensitiveValue{transformValue{f.Value, noop}}, f.Value)
+		if !isZero && f.DefValue != "" {
+			f.DefValue = redacted
+		}
+	case PFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(
+			sensitiveValue{transformValue{f.Value, noop}}, f.Value)
+		if !isZero && f.DefValue != "" {
+			f.DefValue = redacted
+		}
+	}
+}
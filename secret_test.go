@@ -0,0 +1,85 @@
+package flagbind
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecret(t *testing.T) {
+	type Flags struct {
+		Token    Secret `flag:";default-token;API token"`
+		Password string `flag:";hunter2;Password;secret"`
+		Empty    Secret
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		var f Flags
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		require.NoError(t, Bind(fs, &f))
+		assert.Equal(t, "default-token", f.Token.Reveal())
+		assert.Equal(t, "hunter2", f.Password)
+
+		var usage bytes.Buffer
+		fs.SetOutput(&usage)
+		fs.PrintDefaults()
+		assert.NotContains(t, usage.String(), "default-token")
+		assert.NotContains(t, usage.String(), "hunter2")
+		assert.Equal(t, redacted, fs.Lookup("token").DefValue)
+
+		require.NoError(t, fs.Parse([]string{"-token", "abc"}))
+		assert.Equal(t, Secret("abc"), f.Token)
+		assert.Equal(t, redacted, fs.Lookup("token").Value.String())
+	})
+	t.Run("pflag", func(t *testing.T) {
+		var f Flags
+		fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+		require.NoError(t, Bind(fs, &f))
+
+		usage := fs.FlagUsages()
+		assert.NotContains(t, usage, "default-token")
+		assert.NotContains(t, usage, "hunter2")
+		assert.Equal(t, "secret", fs.Lookup("password").Value.Type())
+		assert.Equal(t, "", fs.Lookup("empty").DefValue)
+
+		require.NoError(t, fs.Parse([]string{"--password", "abc"}))
+		assert.Equal(t, "abc", f.Password)
+	})
+}
+
+func TestSensitive(t *testing.T) {
+	var f struct {
+		Key   []byte `flag:";;;sensitive"`
+		Port  int    `flag:";8080;;sensitive"`
+		Empty string `flag:";;;sensitive"`
+	}
+	f.Key = []byte("key")
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Prefix("x-")))
+
+	usage := fs.FlagUsages()
+	assert.NotContains(t, usage, "6b6579")
+	assert.NotContains(t, usage, "8080")
+	assert.Equal(t, redacted, fs.Lookup("x-key").DefValue)
+	assert.Equal(t, "", fs.Lookup("x-empty").DefValue)
+
+	require.NoError(t, fs.Parse([]string{"--x-port", "9090"}))
+	assert.Equal(t, 9090, f.Port)
+	assert.Equal(t, []Setting{
+		{"x-empty", "", OriginDefault},
+		{"x-key", redacted, OriginValue},
+		{"x-port", redacted, OriginFlag},
+	}, Effective(fs))
+
+	var g struct {
+		Keys []string `flag:";;;sensitive"`
+	}
+	g.Keys = []string{"a", "b"}
+	data, err := DumpValues(&g, FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"keys\": \"***\"\n}\n", string(data))
+}
@@ -0,0 +1,93 @@
+package flagbind
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes. It is a flag.Value that accepts a number with an
+// optional unit, such as "512K", "10MiB", or "1.5GB".
+//
+// Units are case insensitive. The SI units kB, MB, GB, TB, and PB are powers
+// of 1000. The IEC units KiB, MiB, GiB, TiB, and PiB, and the single letter
+// units K, M, G, T, and P, are powers of 1024. A trailing B without a prefix
+// is bytes.
+//
+// String uses the largest unit that represents the Size exactly, preferring
+// IEC units.
+type Size uint64
+
+type sizeUnit struct {
+	Suffix string
+	Bytes  uint64
+}
+
+// sizeUnits are ordered from largest to smallest, with IEC before SI, for
+// formatting.
+var sizeUnits = []sizeUnit{
+	{"PiB", 1 << 50}, {"PB", 1e15},
+	{"TiB", 1 << 40}, {"TB", 1e12},
+	{"GiB", 1 << 30}, {"GB", 1e9},
+	{"MiB", 1 << 20}, {"MB", 1e6},
+	{"KiB", 1 << 10}, {"kB", 1e3},
+}
+
+// sizeSuffixes are the additional single letter units accepted by Set.
+var sizeSuffixes = []sizeUnit{
+	{"P", 1 << 50}, {"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20},
+	{"K", 1 << 10}, {"B", 1},
+}
+
+func (s *Size) Set(text string) error {
+	num := strings.TrimSpace(text)
+	mult := uint64(1)
+	for _, units := range [][]sizeUnit{sizeUnits, sizeSuffixes} {
+		for _, unit := range units {
+			if len(num) >= len(unit.Suffix) && strings.EqualFold(
+				num[len(num)-len(unit.Suffix):], unit.Suffix) {
+				num = strings.TrimSpace(num[:len(num)-len(unit.Suffix)])
+				mult = unit.Bytes
+				break
+			}
+		}
+		if mult != 1 {
+			break
+		}
+	}
+
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/mult {
+			return fmt.Errorf("size %q overflows uint64", text)
+		}
+		*s = Size(n * mult)
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("invalid size %q", text)
+	}
+	f *= float64(mult)
+	if f >= math.MaxUint64 {
+		return fmt.Errorf("size %q overflows uint64", text)
+	}
+	*s = Size(math.Round(f))
+	return nil
+}
+
+func (s Size) String() string {
+	if s == 0 {
+		return "0"
+	}
+	for _, unit := range sizeUnits {
+		if uint64(s)%unit.Bytes == 0 {
+			return strconv.FormatUint(uint64(s)/unit.Bytes, 10) +
+				unit.Suffix
+		}
+	}
+	return strconv.FormatUint(uint64(s), 10) + "B"
+}
+
+func (s Size) Type() string { return "size" }
@@ -0,0 +1,49 @@
+package flagbind
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sizeTests = []struct {
+	Text   string
+	Size   Size
+	String string
+	Err    string
+}{
+	{Text: "0", Size: 0, String: "0"},
+	{Text: "100", Size: 100, String: "100B"},
+	{Text: "100b", Size: 100, String: "100B"},
+	{Text: "512K", Size: 512 << 10, String: "512KiB"},
+	{Text: "512 kb", Size: 512e3, String: "500KiB"},
+	{Text: "3kB", Size: 3e3, String: "3kB"},
+	{Text: "10MiB", Size: 10 << 20, String: "10MiB"},
+	{Text: "1.5GB", Size: 1.5e9, String: "1500MB"},
+	{Text: "1.5GiB", Size: 1.5 * (1 << 30), String: "1536MiB"},
+	{Text: "2tb", Size: 2e12, String: "2TB"},
+	{Text: "1P", Size: 1 << 50, String: "1PiB"},
+	{Text: "1.5", Size: 2, String: "2B"},
+	{Text: "-1K", Err: `invalid size "-1K"`},
+	{Text: "1X", Err: `invalid size "1X"`},
+	{Text: "NaN", Err: `invalid size "NaN"`},
+	{Text: "+InfKB", Err: `invalid size "+InfKB"`},
+	{Text: "16384PiB", Err: `size "16384PiB" overflows uint64`},
+}
+
+func TestSize(t *testing.T) {
+	for _, test := range sizeTests {
+		t.Run(test.Text, func(t *testing.T) {
+			assert := assert.New(t)
+			var s Size
+			err := s.Set(test.Text)
+			if test.Err != "" {
+				assert.EqualError(err, test.Err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(test.Size, s)
+			assert.Equal(test.String, s.String())
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package flagbind
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Origin is where the effective value of a flag came from. See Source.
+type Origin int
+
+// The Origins of a flag value, in increasing order of precedence.
+const (
+	// OriginDefault is the Flag Tag <default>, or the zero value.
+	OriginDefault Origin = iota
+
+	// OriginValue is the value that the struct field had when Bind was
+	// called, such as one set by SetDefaults.
+	OriginValue
+
+	// OriginConfig is a value from a config file.
+	OriginConfig
+
+	// OriginSecret is a value from a secret resolver. See SecretLayer.
+	OriginSecret
+
+	// OriginEnv is a value from an environment variable.
+	OriginEnv
+
+	// OriginFlag is a value set on the command line, or by any other
+	// call to FlagSet.Set.
+	OriginFlag
+)
+
+// String returns the name of the Origin, such as "default" or "env".
+func (o Origin) String() string {
+	switch o {
+	case OriginDefault:
+		return "default"
+	case OriginValue:
+		return "value"
+	case OriginConfig:
+		return "config"
+	case OriginSecret:
+		return "secret"
+	case OriginEnv:
+		return "env"
+	case OriginFlag:
+		return "flag"
+	}
+	return "unknown"
+}
+
+// parseOrigin returns the Origin with the String name.
+func parseOrigin(name string) (Origin, bool) {
+	for o := OriginDefault; o <= OriginFlag; o++ {
+		if strings.EqualFold(name, o.String()) {
+			return o, true
+		}
+	}
+	return OriginDefault, false
+}
+
+// MarshalText returns the String of the Origin, so that it is encoded by name
+// in JSON.
+func (o Origin) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// originValue is a flag.Value that records the Origin of its value, so that it
+// is kept with the flag, rather than with the FlagSet. Any call to Set, such
+// as by fs.Parse, records OriginFlag.
+type originValue struct {
+	flag.Value
+	origin Origin
+
+	// reset restores the value and the base Origin that the flag had
+	// before it was first set by SetFrom, or is nil. See saveValue.
+	reset func(replace bool) error
+	base  Origin
+
+	// precedence lists the Origins that Resolve may set the flag from, in
+	// order, or is nil. See the precedence Flag Tag option.
+	precedence []Origin
+}
+
+func (v *originValue) Set(text string) error {
+	if err := v.Value.Set(text); err != nil {
+		return err
+	}
+	v.origin = OriginFlag
+	return nil
+}
+
+func (v *originValue) String() string {
+	if v == nil || v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+func (v *originValue) Type() string {
+	if v, ok := v.Value.(interface{ Type() string }); ok {
+		return v.Type()
+	}
+	return ""
+}
+
+// originBoolValue is an originValue for a boolean flag, which may be set
+// without a value.
+type originBoolValue struct{ *originValue }
+
+func (v originBoolValue) IsBoolFlag() bool { return true }
+
+func (v originBoolValue) String() string {
+	if v.originValue == nil {
+		return "false"
+	}
+	return v.originValue.String()
+}
+
+// newOriginValue wraps v so that it records origin.
+func newOriginValue(v flag.Value, origin Origin) pflag.Value {
+	ov := &originValue{Value: v, origin: origin}
+	if b, ok := v.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return originBoolValue{ov}
+	}
+	return ov
+}
+
+// findOrigin returns the originValue among the Values wrapped by v, if any.
+func findOrigin(v flag.Value) *originValue {
+	for v != nil {
+		switch v := v.(type) {
+		case *originValue:
+			return v
+		case originBoolValue:
+			return v.originValue
+		}
+		v = unwrapValue(v)
+	}
+	return nil
+}
+
+// Source returns the Origin of the effective value of the flag name in fs. A
+// flag set by SetFrom has the Origin passed to it. Otherwise, a flag that
+// has been set, such as by fs.Parse, has OriginFlag. A flag that has not been
+// set has OriginValue if Bind found its field set, or else OriginDefault.
+func Source(fs FlagSet, name string) Origin {
+	return source(fs, name, setFlags(fs))
+}
+
+// source returns the Origin of the flag name in fs, given the set flags.
+func source(fs FlagSet, name string, set map[string]bool) Origin {
+	if origin, ok := getOrigin(fs, name); ok {
+		return origin
+	}
+	if set[name] {
+		return OriginFlag
+	}
+	return OriginDefault
+}
+
+// SetFrom sets the flag name in fs to value, like fs.Set, and records that the
+// value came from origin so that it is reported by Source. This allows
+// loaders of environment variables or config files to report where each
+// value came from. Since the origin is kept until the flag is set again,
+// loaders should be run after fs.Parse, and only set flags that were not
+// already set.
+func SetFrom(fs FlagSet, name, value string, origin Origin) error {
+	return setFrom(fs, name, value, origin, nil)
+}
+
+// setFrom is SetFrom, given the set flags, if known. The first time that the
+// flag name is set, its value and Origin are saved so that Resolve may reset
+// them.
+func setFrom(fs FlagSet, name, value string, origin Origin,
+	set map[string]bool) error {
+	if flagValue(fs, name) == nil {
+		return fs.Set(name, value)
+	}
+	saveFlag(fs, name, set)
+	if err := fs.Set(name, value); err != nil {
+		return err
+	}
+	setOrigin(fs, name, origin)
+	return nil
+}
+
+// saveFlag saves the value and Origin of the flag name in fs, given the set
+// flags, if known, unless they were already saved, and returns its
+// originValue.
+func saveFlag(fs FlagSet, name string, set map[string]bool) *originValue {
+	v := flagValue(fs, name)
+	if ov := findOrigin(v); ov != nil && ov.reset != nil {
+		return ov
+	}
+	if set == nil {
+		set = setFlags(fs)
+	}
+	base := source(fs, name, set)
+	setOrigin(fs, name, base)
+	ov := findOrigin(flagValue(fs, name))
+	restore := saveValue(v)
+	ov.base = base
+	ov.reset = func(replace bool) error {
+		ov.origin = ov.base
+		return restore(replace)
+	}
+	return ov
+}
+
+// resettableValue is implemented by the Values of this package that
+// accumulate across calls to Set.
+type resettableValue interface {
+	// save returns a func that restores the current value, such that
+	// the next Set behaves as it would have now.
+	save() (restore func())
+}
+
+// saveValue returns a func that restores the current value of v. If replace
+// is true, slices are emptied instead, since their next Set would append to
+// the restored value.
+func saveValue(v flag.Value) func(replace bool) error {
+	v = baseValue(v)
+	switch v := v.(type) {
+	case resettableValue:
+		restore := v.save()
+		return func(bool) error {
+			restore()
+			return nil
+		}
+	case pflag.SliceValue:
+		saved := append([]string(nil), v.GetSlice()...)
+		return func(replace bool) error {
+			if replace {
+				return v.Replace(nil)
+			}
+			return v.Replace(saved)
+		}
+	}
+
+	// Most Values are a pointer to the field itself, which may be
+	// restored directly.
+	if ptr := reflect.ValueOf(v); ptr.Kind() == reflect.Ptr && !ptr.IsNil() {
+		switch ptr.Elem().Kind() {
+		case reflect.Struct, reflect.Map:
+		default:
+			saved := reflect.New(ptr.Elem().Type()).Elem()
+			saved.Set(ptr.Elem())
+			return func(bool) error {
+				ptr.Elem().Set(saved)
+				return nil
+			}
+		}
+	}
+	text := v.String()
+	return func(bool) error {
+		if v.String() == text {
+			return nil
+		}
+		return v.Set(text)
+	}
+}
+
+// setOrigin records the origin of the flag name in fs, by wrapping its Value
+// the first time.
+func setOrigin(fs FlagSet, name string, origin Origin) {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		if v := findOrigin(f.Value); v != nil {
+			v.origin = origin
+			return
+		}
+		f.Value = newOriginValue(f.Value, origin)
+	case PFlagSet:
+		f := fs.Lookup(name)
+		if v := findOrigin(f.Value); v != nil {
+			v.origin = origin
+			return
+		}
+		f.Value = newOriginValue(f.Value, origin)
+	}
+}
+
+// setPrecedence records the Origins, in order, that Resolve may set the flag
+// name in fs from.
+func setPrecedence(fs FlagSet, name string, precedence []Origin) {
+	if findOrigin(flagValue(fs, name)) == nil {
+		setOrigin(fs, name, Source(fs, name))
+	}
+	findOrigin(flagValue(fs, name)).precedence = precedence
+}
+
+// getOrigin returns the recorded origin of the flag name in fs, if any.
+func getOrigin(fs FlagSet, name string) (Origin, bool) {
+	v := findOrigin(flagValue(fs, name))
+	if v == nil {
+		return OriginDefault, false
+	}
+	return v.origin, true
+}
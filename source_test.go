@@ -0,0 +1,42 @@
+package flagbind
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource(t *testing.T) {
+	var f struct {
+		Timeout int `flag:";5"`
+		Host    string
+		Port    int
+		Level   string
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--host", "example.com"}))
+	require.NoError(t, SetFrom(fs, "port", "80", OriginEnv))
+	require.NoError(t, SetFrom(fs, "level", "debug", OriginConfig))
+	assert.Error(t, SetFrom(fs, "timeout", "x", OriginEnv))
+
+	assert.Equal(t, OriginDefault, Source(fs, "timeout"))
+	assert.Equal(t, OriginFlag, Source(fs, "host"))
+	assert.Equal(t, OriginEnv, Source(fs, "port"))
+	assert.Equal(t, OriginConfig, Source(fs, "level"))
+	assert.Equal(t, 80, f.Port)
+	assert.Equal(t, "env", Source(fs, "port").String())
+	assert.Equal(t, "int", fs.Lookup("port").Value.Type())
+
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	std.SetOutput(ioutil.Discard)
+	var g struct{ Host string }
+	require.NoError(t, Bind(std, &g))
+	assert.Equal(t, OriginDefault, Source(std, "host"))
+	require.NoError(t, std.Parse([]string{"-host", "example.com"}))
+	assert.Equal(t, OriginFlag, Source(std, "host"))
+}
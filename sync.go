@@ -0,0 +1,145 @@
+package flagbind
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Values provides safe concurrent access to the struct fields bound with the
+// Synchronized Option, by flag name, so that values updated by Resolve or
+// Watch may be read from any goroutine. The zero Values is ready to use.
+//
+// The typed getters, such as Int, panic like the reflect package if the flag
+// was not bound with Synchronized, or if its field is not of a compatible
+// kind.
+type Values struct {
+	mu     sync.RWMutex
+	fields map[string]reflect.Value
+}
+
+// add records the field pointer ptr bound to the flag name.
+func (vals *Values) add(name string, ptr reflect.Value) {
+	vals.mu.Lock()
+	defer vals.mu.Unlock()
+	if vals.fields == nil {
+		vals.fields = make(map[string]reflect.Value)
+	}
+	vals.fields[name] = ptr
+}
+
+// field returns the field bound to the flag name. The caller must hold the
+// read lock.
+func (vals *Values) field(name string) reflect.Value {
+	ptr, ok := vals.fields[name]
+	if !ok {
+		panic("flagbind: flag not synchronized: " + name)
+	}
+	return ptr.Elem()
+}
+
+// Read calls fn while holding the read lock, so that several fields may be
+// read consistently, directly from the bound struct. The fields must not be
+// written, and the getters of vals must not be called, from fn.
+func (vals *Values) Read(fn func()) {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	fn()
+}
+
+// Get returns a copy of the field bound to the flag name. Slices, maps, and
+// pointers still refer to the same memory as the field.
+func (vals *Values) Get(name string) interface{} {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	return vals.field(name).Interface()
+}
+
+// String returns the value of the string field bound to the flag name.
+func (vals *Values) String(name string) string {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	return vals.field(name).String()
+}
+
+// Bool returns the value of the bool field bound to the flag name.
+func (vals *Values) Bool(name string) bool {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	return vals.field(name).Bool()
+}
+
+// Int returns the value of the signed integer field bound to the flag name.
+func (vals *Values) Int(name string) int64 {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	return vals.field(name).Int()
+}
+
+// Uint returns the value of the unsigned integer field bound to the flag name.
+func (vals *Values) Uint(name string) uint64 {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	return vals.field(name).Uint()
+}
+
+// Float returns the value of the floating point field bound to the flag name.
+func (vals *Values) Float(name string) float64 {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	return vals.field(name).Float()
+}
+
+// Duration returns the value of the time.Duration field bound to the flag
+// name.
+func (vals *Values) Duration(name string) time.Duration {
+	return time.Duration(vals.Int(name))
+}
+
+// Strings returns a copy of the []string field bound to the flag name.
+func (vals *Values) Strings(name string) []string {
+	vals.mu.RLock()
+	defer vals.mu.RUnlock()
+	strs := vals.field(name).Interface().([]string)
+	return append([]string(nil), strs...)
+}
+
+// lockedValue is a flag.Value that holds the lock of a Values while it is Set
+// or read.
+type lockedValue struct {
+	transformValue
+	mu *sync.RWMutex
+}
+
+func (v lockedValue) Set(text string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.transformValue.Set(text)
+}
+
+func (v lockedValue) String() string {
+	if v.mu == nil {
+		return ""
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.transformValue.String()
+}
+
+// synchronizeFlag wraps the Value of the flag name, which is bound to the field
+// pointer ptr, so that it is Set and read under the lock of vals.
+func synchronizeFlag(fs FlagSet, name string, ptr reflect.Value,
+	vals *Values) {
+	noop := func(text string) (string, error) { return text, nil }
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(
+			lockedValue{transformValue{f.Value, noop}, &vals.mu}, f.Value)
+	case PFlagSet:
+		f := fs.Lookup(name)
+		f.Value = keepBoolFlag(
+			lockedValue{transformValue{f.Value, noop}, &vals.mu}, f.Value)
+	}
+	vals.add(name, ptr)
+}
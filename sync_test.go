@@ -0,0 +1,96 @@
+package flagbind
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynchronized(t *testing.T) {
+	var f struct {
+		Host    string `flag:";localhost"`
+		Port    uint16
+		Retries int `flag:";3;;onchange=test-sync"`
+		Verbose bool
+		Ratio   float64
+		Timeout time.Duration `flag:";5s"`
+		Tags    []string
+	}
+	var vals Values
+	RegisterOnChange("test-sync", func(name, value string) {
+		// Reading from the callback must not deadlock.
+		assert.Equal(t, value, strconv.Itoa(int(vals.Int(name))))
+	})
+
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Synchronized(&vals)))
+	require.NoError(t, fs.Parse([]string{"--port", "80", "--verbose",
+		"--ratio", "0.5", "--tags", "a,b"}))
+
+	assert.Equal(t, "localhost", vals.String("host"))
+	assert.Equal(t, uint64(80), vals.Uint("port"))
+	assert.Equal(t, int64(3), vals.Int("retries"))
+	assert.True(t, vals.Bool("verbose"))
+	assert.Equal(t, 0.5, vals.Float("ratio"))
+	assert.Equal(t, 5*time.Second, vals.Duration("timeout"))
+	assert.Equal(t, []string{"a", "b"}, vals.Strings("tags"))
+	assert.Equal(t, uint16(80), vals.Get("port"))
+	vals.Read(func() { assert.Equal(t, "localhost", f.Host) })
+	assert.Panics(t, func() { vals.Int("missing") })
+
+	// Inspect binds a copy, which must not replace the bound fields.
+	_, err := Inspect(&f, Synchronized(&vals))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", vals.String("host"))
+	f.Host = "example.com"
+	assert.Equal(t, "example.com", vals.String("host"))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, SetFrom(fs, "retries",
+				strconv.Itoa(i), OriginConfig))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		vals.Int("retries")
+		_ = fs.Lookup("retries").Value.String()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(99), vals.Int("retries"))
+}
+
+func TestSynchronizedStructMap(t *testing.T) {
+	var f struct {
+		Servers map[string]struct{ Port int } `flag:";;;keys=a"`
+	}
+	var vals Values
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Synchronized(&vals)))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, fs.Set("servers-a-port", strconv.Itoa(i)))
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			vals.Read(func() {
+				assert.Equal(t, 99, f.Servers["a"].Port)
+			})
+			return
+		default:
+			vals.Read(func() { _ = f.Servers["a"].Port })
+		}
+	}
+}
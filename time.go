@@ -0,0 +1,183 @@
+package flagbind
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var locationType = reflect.TypeOf((*time.Location)(nil))
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// relativeTime returns text formatted with the layout if it is a time relative
+// to now, such as "now", "now-24h", or "today+9h", where "today" is midnight
+// in the local time zone at the start of the day. Otherwise text is returned
+// as is.
+func relativeTime(text string, now time.Time, layout string) string {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	var base time.Time
+	switch {
+	case strings.HasPrefix(lower, "now"):
+		base = now
+		lower = lower[len("now"):]
+	case strings.HasPrefix(lower, "today"):
+		y, m, d := now.Date()
+		base = time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+		lower = lower[len("today"):]
+	default:
+		return text
+	}
+	if lower != "" {
+		if lower[0] != '+' && lower[0] != '-' {
+			return text
+		}
+		offset, err := time.ParseDuration(lower)
+		if err != nil {
+			return text
+		}
+		base = base.Add(offset)
+	}
+	return base.Format(timeLayout(layout))
+}
+
+// timeLayouts are the names of the layout constants in the time package that
+// may be used with the layout=<layout> tag option.
+var timeLayouts = map[string]string{
+	"ansic":       time.ANSIC,
+	"unixdate":    time.UnixDate,
+	"rubydate":    time.RubyDate,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"kitchen":     time.Kitchen,
+	"stamp":       time.Stamp,
+	"stampmilli":  time.StampMilli,
+	"stampmicro":  time.StampMicro,
+	"stampnano":   time.StampNano,
+	"datetime":    "2006-01-02 15:04:05",
+	"dateonly":    "2006-01-02",
+	"timeonly":    "15:04:05",
+}
+
+// timeLayout returns the named layout, or layout itself if it is not a known
+// name. The default is time.RFC3339Nano.
+func timeLayout(layout string) string {
+	if layout == "" {
+		return time.RFC3339Nano
+	}
+	if named, ok := timeLayouts[strings.ToLower(layout)]; ok {
+		return named
+	}
+	return layout
+}
+
+// timeValue is a time.Time flag.Value that uses layout to parse and format.
+type timeValue struct {
+	value  *time.Time
+	layout string
+}
+
+func newTimeValue(p *time.Time, layout string) *timeValue {
+	return &timeValue{p, timeLayout(layout)}
+}
+
+func (t *timeValue) Set(text string) error {
+	v, err := time.Parse(t.layout, text)
+	if err != nil {
+		return err
+	}
+	*t.value = v
+	return nil
+}
+
+func (t *timeValue) String() string {
+	if t.value == nil || t.value.IsZero() {
+		return ""
+	}
+	return t.value.Format(t.layout)
+}
+
+func (t *timeValue) Type() string { return "time" }
+
+// timeSliceValue is a []time.Time flag.Value that uses layout to parse and
+// format. Each occurrence accepts one or more comma separated times, or just
+// one time if the layout contains a comma, such as time.RFC1123. The first
+// occurrence replaces any default, and subsequent occurrences append.
+type timeSliceValue struct {
+	value   *[]time.Time
+	layout  string
+	changed bool
+}
+
+func newTimeSliceValue(p *[]time.Time, layout string) *timeSliceValue {
+	return &timeSliceValue{value: p, layout: timeLayout(layout)}
+}
+
+func (t *timeSliceValue) Set(text string) error {
+	texts := []string{text}
+	if !strings.Contains(t.layout, ",") {
+		texts = strings.Split(text, ",")
+	}
+	var times []time.Time
+	for _, text := range texts {
+		v, err := time.Parse(t.layout, strings.TrimSpace(text))
+		if err != nil {
+			return err
+		}
+		times = append(times, v)
+	}
+	if !t.changed {
+		*t.value = times
+	} else {
+		*t.value = append(*t.value, times...)
+	}
+	t.changed = true
+	return nil
+}
+
+func (t *timeSliceValue) String() string {
+	if t.value == nil || len(*t.value) == 0 {
+		return ""
+	}
+	times := make([]string, len(*t.value))
+	for i, v := range *t.value {
+		times[i] = v.Format(t.layout)
+	}
+	return "[" + strings.Join(times, ",") + "]"
+}
+
+func (t *timeSliceValue) Type() string { return "timeSlice" }
+
+func (t *timeSliceValue) save() func() {
+	saved, changed := *t.value, t.changed
+	saved = saved[:len(saved):len(saved)]
+	return func() { *t.value, t.changed = saved, changed }
+}
+
+// locationValue is a *time.Location flag.Value that uses time.LoadLocation.
+type locationValue struct {
+	value **time.Location
+}
+
+func (l *locationValue) Set(text string) error {
+	loc, err := time.LoadLocation(text)
+	if err != nil {
+		return err
+	}
+	*l.value = loc
+	return nil
+}
+
+func (l *locationValue) String() string {
+	if l.value == nil || *l.value == nil {
+		return ""
+	}
+	return (*l.value).String()
+}
+
+func (l *locationValue) Type() string { return "location" }
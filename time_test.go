@@ -0,0 +1,39 @@
+package flagbind
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2020, 5, 6, 7, 8, 9, 0, time.UTC)
+	for text, exp := range map[string]string{
+		"now":        "2020-05-06T07:08:09Z",
+		"NOW-24h":    "2020-05-05T07:08:09Z",
+		"now+1h30m":  "2020-05-06T08:38:09Z",
+		"today":      "2020-05-06T00:00:00Z",
+		"today+9h":   "2020-05-06T09:00:00Z",
+		"nowhere":    "nowhere",
+		"now-banana": "now-banana",
+		"2020-01-01": "2020-01-01",
+	} {
+		assert.Equal(t, exp, relativeTime(text, now, "rfc3339"), text)
+	}
+}
+
+func TestBindRelativeTime(t *testing.T) {
+	var f struct {
+		Since time.Time `flag:";now-24h"`
+		Day   time.Time `flag:";today;;layout=dateonly"`
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	before := time.Now()
+	require.NoError(t, Bind(fs, &f))
+	assert.WithinDuration(t, before.Add(-24*time.Hour), f.Since, time.Minute)
+	assert.Equal(t, before.Format("2006-01-02"), f.Day.Format("2006-01-02"))
+	assert.Equal(t, "now-24h", fs.Lookup("since").DefValue)
+}
@@ -1,6 +1,12 @@
 package flagbind
 
-import "net/url"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
 
 type URL url.URL
 
@@ -18,3 +24,50 @@ func (u URL) String() string {
 }
 
 func (u URL) Type() string { return "URL" }
+
+// validURL is a URL that is validated when it is Set.
+type validURL struct {
+	*URL
+	schemes     []string
+	requireHost bool
+}
+
+func newURLValue(u *url.URL, tag flagTag) pflag.Value {
+	if len(tag.Schemes) == 0 && !tag.RequireHost {
+		return (*URL)(u)
+	}
+	return validURL{(*URL)(u), tag.Schemes, tag.RequireHost}
+}
+
+func (u validURL) String() string {
+	if u.URL == nil {
+		return ""
+	}
+	return u.URL.String()
+}
+
+func (u validURL) Set(text string) error {
+	var parsed URL
+	if err := parsed.Set(text); err != nil {
+		return err
+	}
+	if len(u.schemes) > 0 && !containsString(u.schemes,
+		strings.ToLower(parsed.Scheme)) {
+		return fmt.Errorf("URL scheme %q is not one of: %v",
+			parsed.Scheme, strings.Join(u.schemes, ", "))
+	}
+	if u.requireHost && parsed.Host == "" {
+		return fmt.Errorf("URL must have a host")
+	}
+	*u.URL = parsed
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package flagbind
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/spf13/pflag"
+)
+
+// setUsage applies the DeclarationOrder and UsageWidth options to fs.
+func (b bind) setUsage(fs FlagSet) {
+	switch fs := fs.(type) {
+	case *pflag.FlagSet:
+		if b.DeclarationOrder {
+			fs.SortFlags = false
+		}
+		// The default Usage of pflag neither wraps nor hides the
+		// defaults hidden by hideZeroDefault.
+		custom := b.UsageWidth > 0
+		for _, name := range b.State.Order {
+			if custom {
+				break
+			}
+			custom = hasZeroDefault(fs.Lookup(name).Value)
+		}
+		if custom {
+			setPFlagUsage(fs, b.UsageWidth)
+		}
+	case *flag.FlagSet:
+		if b.UsageWidth > 0 {
+			for _, name := range b.State.Order {
+				f := fs.Lookup(name)
+				f.Usage = wrapText(f.Usage, b.UsageWidth-stdUsageIndent)
+			}
+		}
+		if b.DeclarationOrder {
+			state := b.State
+			fs.Usage = func() {
+				printUsageHeader(fs)
+				for _, f := range state.orderedFlags(fs) {
+					printFlagDefault(fs, f)
+				}
+			}
+		}
+	}
+	if b.UsageTemplate != nil {
+		b.setUsageTemplate(fs)
+	}
+}
+
+// UsageData is the data passed to the template set by the UsageTemplate
+// Option.
+type UsageData struct {
+	// Name is the name of the FlagSet.
+	Name string
+
+	// Flags describes each flag defined by Bind, in the order that they
+	// were defined, including hidden flags.
+	Flags []FlagInfo
+}
+
+// UsageFuncs returns the funcs that may be used by a UsageTemplate:
+//
+//      option <info> <name> - The value of the named option of the FlagInfo,
+//      such as `{{option . "oneof"}}`, or "" if it is not set.
+//
+//      hasOption <info> <name> - Whether the FlagInfo has the named option,
+//      such as `{{if hasOption . "requires"}}`.
+//
+//      env <prefix> <name> - The EnvName of the flag name, such as
+//      `{{env "APP_" .Name}}`.
+//
+//      wrap <width> <text> - The text wrapped at spaces to at most width
+//      columns.
+func UsageFuncs() template.FuncMap {
+	return template.FuncMap{
+		"option": func(info FlagInfo, name string) string {
+			val, _ := info.Option(name)
+			return val
+		},
+		"hasOption": func(info FlagInfo, name string) bool {
+			_, ok := info.Option(name)
+			return ok
+		},
+		"env": EnvName,
+		"wrap": func(width int, text string) string {
+			return wrapText(text, width)
+		},
+	}
+}
+
+// setUsageTemplate sets the Usage of fs to execute the UsageTemplate.
+func (b bind) setUsageTemplate(fs FlagSet) {
+	tmpl, state := b.UsageTemplate, b.State
+	usage := func(name string, out io.Writer) {
+		data := UsageData{Name: name}
+		for _, name := range state.Order {
+			if info, ok := state.flagInfo(fs, name); ok {
+				data.Flags = append(data.Flags, info)
+			}
+		}
+		if err := tmpl.Execute(out, data); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+	switch fs := fs.(type) {
+	case *pflag.FlagSet:
+		fs.Usage = func() { usage(pflagName(fs), os.Stderr) }
+	case *flag.FlagSet:
+		fs.Usage = func() { usage(fs.Name(), fs.Output()) }
+	}
+}
+
+// zeroDefaultValue is a flag.Value whose default is hidden from the usage,
+// since its field had the zero value of its type. Its DefValue is empty, and
+// the zero zeroDefaultValue has an empty String, so flag.PrintDefaults does
+// not display it. With pflag, see pflagUsages.
+type zeroDefaultValue struct {
+	flag.Value
+}
+
+func (v zeroDefaultValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+func (v zeroDefaultValue) Type() string {
+	if v, ok := v.Value.(interface{ Type() string }); ok {
+		return v.Type()
+	}
+	return ""
+}
+
+// hideZeroDefault hides the default of the flag name, which must have the zero
+// value of its type. Bool flags are left as is, since both flag packages
+// already hide a false default and rely on IsBoolFlag, which
+// zeroDefaultValue does not implement.
+func hideZeroDefault(fs FlagSet, name string) {
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		f := fs.Lookup(name)
+		if isBoolFlag(f.Value) {
+			return
+		}
+		f.Value = zeroDefaultValue{f.Value}
+		f.DefValue = ""
+	case PFlagSet:
+		f := fs.Lookup(name)
+		if f.NoOptDefVal != "" || isBoolFlag(f.Value) {
+			return
+		}
+		f.Value = zeroDefaultValue{f.Value}
+		f.DefValue = ""
+	}
+}
+
+// hasZeroDefault reports whether v, or any Value that it wraps, is a
+// zeroDefaultValue.
+func hasZeroDefault(v flag.Value) bool {
+	for ; v != nil; v = unwrapValue(v) {
+		if _, ok := v.(zeroDefaultValue); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenDefaultValue is a pflag.Value with an empty String, which pflag takes
+// to be a zero default.
+type hiddenDefaultValue struct {
+	pflag.Value
+}
+
+func (v hiddenDefaultValue) String() string { return "" }
+
+// pflagUsages returns fs.FlagUsagesWrapped(cols), without the default of any
+// flag hidden by hideZeroDefault. Unlike the flag package, pflag decides
+// whether to display the default of most Values by their current String, so
+// each such Value is replaced by a hiddenDefaultValue while the usage is
+// formatted.
+func pflagUsages(fs *pflag.FlagSet, cols int) string {
+	var hidden []*pflag.Flag
+	fs.VisitAll(func(f *pflag.Flag) {
+		if hasZeroDefault(f.Value) {
+			hidden = append(hidden, f)
+		}
+	})
+	for _, f := range hidden {
+		f.Value = hiddenDefaultValue{f.Value}
+	}
+	defer func() {
+		for _, f := range hidden {
+			f.Value = f.Value.(hiddenDefaultValue).Value
+		}
+	}()
+	return fs.FlagUsagesWrapped(cols)
+}
+
+// setPFlagUsage sets the Usage of fs to print the header and flags like the
+// default Usage of pflag, using pflagUsages.
+func setPFlagUsage(fs *pflag.FlagSet, cols int) {
+	fs.Usage = func() {
+		out := pflagOutput(fs)
+		fmt.Fprintf(out, "Usage of %s:\n", pflagName(fs))
+		fmt.Fprint(out, pflagUsages(fs, cols))
+	}
+}
+
+// isBoolFlag reports whether v implements IsBoolFlag and returns true.
+func isBoolFlag(v interface{}) bool {
+	b, ok := v.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// orderedFlags returns all flags in fs, with those bound by Bind first in
+// the order they were bound, followed by the rest in alphabetical order.
+func (s *bindState) orderedFlags(fs *flag.FlagSet) []*flag.Flag {
+	flags := make([]*flag.Flag, 0, len(s.Order))
+	for _, name := range s.Order {
+		if f := fs.Lookup(name); f != nil {
+			flags = append(flags, f)
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := s.Flags[f.Name]; !ok {
+			flags = append(flags, f)
+		}
+	})
+	return flags
+}
+
+// printUsageHeader prints the same header as the default Usage of fs.
+func printUsageHeader(fs *flag.FlagSet) {
+	if fs.Name() == "" {
+		fmt.Fprintf(fs.Output(), "Usage:\n")
+		return
+	}
+	fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+}
+
+// printFlagDefault prints f to the output of fs as it would be printed by
+// fs.PrintDefaults.
+func printFlagDefault(fs *flag.FlagSet, f *flag.Flag) {
+	// A FlagSet with just this flag formats it the same way.
+	single := flag.NewFlagSet("", flag.ContinueOnError)
+	single.SetOutput(fs.Output())
+	single.Var(f.Value, f.Name, f.Usage)
+	single.Lookup(f.Name).DefValue = f.DefValue
+	single.PrintDefaults()
+}
+
+// stdUsageIndent is the column at which flag.PrintDefaults starts each line of
+// usage.
+const stdUsageIndent = 8
+
+// wrapText wraps text at spaces so that each line is at most width runes,
+// unless a single word is longer. Existing newlines and the spaces between
+// words on the same line are preserved.
+func wrapText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	var sb strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		var n int // Length of the current line.
+		for line != "" {
+			// Split off the leading spaces and the word after them.
+			word := strings.TrimLeft(line, " ")
+			space := line[:len(line)-len(word)]
+			if end := strings.IndexByte(word, ' '); end >= 0 {
+				word = word[:end]
+			}
+			line = line[len(space)+len(word):]
+
+			spaceLen := utf8.RuneCountInString(space)
+			wordLen := utf8.RuneCountInString(word)
+			if n > 0 && word != "" && n+spaceLen+wordLen > width {
+				sb.WriteString("\n")
+				n, space, spaceLen = 0, "", 0
+			}
+			sb.WriteString(space)
+			sb.WriteString(word)
+			n += spaceLen + wordLen
+		}
+	}
+	return sb.String()
+}
+
+// pflagOutput returns the output of fs, which pflag does not export.
+func pflagOutput(fs *pflag.FlagSet) io.Writer {
+	output := settable(reflect.ValueOf(fs).Elem().FieldByName("output"))
+	if out, ok := output.Interface().(io.Writer); ok && out != nil {
+		return out
+	}
+	return os.Stderr
+}
+
+// pflagName returns the name of fs, which pflag does not export.
+func pflagName(fs *pflag.FlagSet) string {
+	return reflect.ValueOf(fs).Elem().FieldByName("name").String()
+}
@@ -0,0 +1,300 @@
+package flagbind
+
+import (
+	"bytes"
+	"flag"
+	"net/url"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderFlags struct {
+	Zeta  bool
+	Alpha string `flag:";default;Alpha usage"`
+	Mid   struct {
+		Beta int
+	}
+}
+
+func TestDeclarationOrderSTDFlag(t *testing.T) {
+	var f orderFlags
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	fs.Bool("extra", false, "")
+	require.NoError(t, Bind(fs, &f, DeclarationOrder()))
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.Usage()
+	assert.Equal(t, `Usage of cmd:
+  -zeta
+    	
+  -alpha string
+    	Alpha usage (default "default")
+  -mid-beta int
+    	
+  -extra
+    	
+`, usage.String())
+}
+
+func TestDeclarationOrderPFlag(t *testing.T) {
+	var f orderFlags
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, DeclarationOrder()))
+
+	var names []string
+	for _, line := range strings.Split(fs.FlagUsages(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	assert.Equal(t, []string{"--zeta", "--alpha", "--mid-beta"}, names)
+}
+
+type wrapFlags struct {
+	Long  string   `flag:"long,l;;This usage is long enough that it must be wrapped onto multiple lines to fit"`
+	_     struct{} `use:"within the width, even with extended usage."`
+	Short bool     `flag:";;Short usage"`
+}
+
+func TestUsageWidthSTDFlag(t *testing.T) {
+	var f wrapFlags
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, UsageWidth(40)))
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.PrintDefaults()
+	assert.Equal(t, `  -long string
+    	This usage is long enough that
+    	it must be wrapped onto multiple
+    	lines to fit within the width,
+    	even with extended usage.
+  -short
+    	Short usage
+`, usage.String())
+}
+
+func TestUsageWidthPFlag(t *testing.T) {
+	var f wrapFlags
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, UsageWidth(60)))
+	fs.String("after-bind", "x", "Defined after Bind, which is aligned and wrapped too")
+
+	var usage bytes.Buffer
+	fs.SetOutput(&usage)
+	fs.Usage()
+	assert.Equal(t, `Usage of :
+      --after-bind string   Defined after Bind, which
+                            is aligned and wrapped too
+                            (default "x")
+  -l, --long string         This usage is long enough
+                            that it must be wrapped
+                            onto multiple lines to fit
+                            within the width, even
+                            with extended usage.
+      --short               Short usage
+`, usage.String())
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, "héllo wörld\nagain", wrapText("héllo wörld again", 11))
+	assert.Equal(t, "a  b\nc", wrapText("a  b c", 4))
+	assert.Equal(t, "  indented\nlong", wrapText("  indented long", 10))
+	assert.Equal(t, "verylongword\nx", wrapText("verylongword x", 4))
+	assert.Equal(t, "a\n\nb", wrapText("a\n\nb", 4))
+}
+
+func TestPrintDefaultsWrapped(t *testing.T) {
+	type wrappedFlags struct {
+		Mode    string `flag:";a;Mode;choices=a|b"`
+		Home    string `flag:";/home;Home;expand-env"`
+		Key     string `flag:";;Key;sensitive"`
+		Debug   bool   `flag:";;Debug;expand-env"`
+		Port    int    `flag:";8080;Port"`
+		Servers map[string]struct {
+			Host string `flag:";;Host"`
+		} `flag:";;;keys=a"`
+	}
+
+	var f wrappedFlags
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Synchronized(&Values{})))
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	fs.PrintDefaults()
+	assert.Equal(t, `  -debug
+    	Debug
+  -home value
+    	Home (default /home)
+  -key value
+    	Key
+  -mode value
+    	Mode (default a)
+  -port value
+    	Port (default 8080)
+  -servers-a-host value
+    	Host
+`, out.String())
+
+	f = wrappedFlags{}
+	pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(pfs, &f, Synchronized(&Values{})))
+	assert.Equal(t, `      --debug                   Debug
+      --home string             Home (default "/home")
+      --key string              Key
+      --mode string             Mode (default "a")
+      --port int                Port (default 8080)
+      --servers-a-host string   Host
+`, pfs.FlagUsages())
+}
+
+func TestPrintDefaultsValidated(t *testing.T) {
+	var f struct {
+		Endpoint url.URL `flag:";;Endpoint;require-host"`
+		Config   File    `flag:";;Config;exists"`
+		Out      Dir     `flag:";;Out;create"`
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	fs.PrintDefaults()
+	assert.Equal(t, `  -config value
+    	Config
+  -endpoint value
+    	Endpoint
+  -out value
+    	Out
+`, out.String())
+}
+
+func TestHideZeroDefaults(t *testing.T) {
+	type zeroFlags struct {
+		Floats  []float64 `flag:";;Floats"`
+		Size    Size      `flag:";;Size"`
+		Verbose bool      `flag:";;Verbose"`
+		Set     []float64 `flag:";1.5;Set"`
+		Tagged  []float64 `flag:";;Tagged;skip-zero-default"`
+	}
+
+	var f zeroFlags
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	fs.Usage()
+	assert.Contains(t, out.String(), "Floats (default [])")
+	assert.NotContains(t, out.String(), "Tagged (default")
+
+	f = zeroFlags{}
+	fs = pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, HideZeroDefaults()))
+	out.Reset()
+	fs.SetOutput(&out)
+	fs.Usage()
+	usage := out.String()
+	assert.Contains(t, usage, "Usage of :")
+	assert.NotContains(t, usage, "Floats (default")
+	assert.NotContains(t, usage, "Size (default")
+	assert.NotContains(t, usage, "Verbose (default")
+	assert.Contains(t, usage, "Set (default 1.5)")
+	assert.Equal(t, "[]", fs.Lookup("floats").Value.String())
+	require.NoError(t, fs.Parse([]string{"--floats", "1", "--verbose"}))
+	assert.Equal(t, []float64{1}, f.Floats)
+	assert.True(t, f.Verbose)
+	assert.Contains(t, fs.FlagUsages(), "Floats (default")
+
+	f = zeroFlags{}
+	std := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(std, &f, HideZeroDefaults()))
+	assert.Equal(t, "0", std.Lookup("size").Value.String())
+	out.Reset()
+	std.SetOutput(&out)
+	std.PrintDefaults()
+	assert.NotContains(t, out.String(), "default")
+	require.NoError(t, std.Parse([]string{"-size", "1KB", "-verbose"}))
+	assert.True(t, f.Verbose)
+}
+
+func TestUsageFunc(t *testing.T) {
+	var f struct {
+		Host string `flag:";;Host name"`
+		Port int    `flag:";;;choices=80|443"`
+	}
+	usageFunc := UsageFunc(func(info FlagInfo) string {
+		if choices, ok := info.Option("choices"); ok {
+			return strings.TrimSpace(info.Usage + " [" + choices + "]")
+		}
+		return info.Usage + " [" + info.Path + "]"
+	})
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, usageFunc))
+	assert.Equal(t, "Host name [Host]", fs.Lookup("host").Usage)
+	assert.Equal(t, "[80|443]", fs.Lookup("port").Usage)
+
+	infos, err := Inspect(&f, usageFunc)
+	require.NoError(t, err)
+	assert.Equal(t, "Host name [Host]", infos[0].Usage)
+}
+
+func TestUsageTemplate(t *testing.T) {
+	var f struct {
+		Host string `flag:";localhost;Host name"`
+		File string `flag:";;Input file;oneof=input"`
+		URL  string `flag:";;Input URL;oneof=input"`
+	}
+	tmpl := template.Must(template.New("usage").Funcs(UsageFuncs()).Parse(
+		`{{.Name}} flags:
+{{range .Flags}}{{if not .Hidden}}  --{{.Name}} ({{env "APP_" .Name}})` +
+			`{{if .Default}} [{{.Default}}]{{end}}` +
+			`{{if hasOption . "oneof"}} <{{option . "oneof"}}>{{end}}
+    {{wrap 10 .Usage}}
+{{end}}{{end}}`))
+	const want = `app flags:
+  --host (APP_HOST) [localhost]
+    Host name
+  --file (APP_FILE) <input>
+    Input file
+  --url (APP_URL) <input>
+    Input URL
+`
+
+	std := flag.NewFlagSet("app", flag.ContinueOnError)
+	require.NoError(t, Bind(std, &f, UsageTemplate(tmpl)))
+	var out bytes.Buffer
+	std.SetOutput(&out)
+	std.Usage()
+	assert.Equal(t, want, out.String())
+
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, UsageTemplate(tmpl)))
+	assert.Equal(t, "app", pflagName(fs))
+	assert.NotNil(t, fs.Usage)
+}
+
+func TestTranslate(t *testing.T) {
+	var f struct {
+		Host    string `flag:";;Host name"`
+		Port    int    `flag:";;Port"`
+		Timeout int
+		_       struct{} `flag:"timeout;;Timeout"`
+	}
+	messages := map[string]string{
+		"Host name": "Nom d'hôte",
+		"Timeout":   "Délai",
+	}
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f, Translate(func(key string) string {
+		return messages[key]
+	})))
+	assert.Equal(t, "Nom d'hôte", fs.Lookup("host").Usage)
+	assert.Equal(t, "Port", fs.Lookup("port").Usage)
+	assert.Equal(t, "Délai", fs.Lookup("timeout").Usage)
+}
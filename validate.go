@@ -0,0 +1,120 @@
+package flagbind
+
+import (
+	"flag"
+
+	"github.com/spf13/pflag"
+)
+
+// Validate checks the constraints set by the `requires`, `conflicts`, `oneof`
+// and `at-most-one` Flag Tag <options> against the flags that have been set in
+// fs, and returns the first violation found, such as ErrorFlagRequires,
+// ErrorFlagConflicts or ErrorFlagOneOf. If more than one flag in a group is
+// set, ErrorFlagConflicts names the first two.
+// Call Validate after fs.Parse, with the same v and opts that were passed to
+// Bind, such as from a cobra.Command PreRunE.
+//
+// Validate works with both the flag and pflag packages. A flag is set if it
+// was visited by FlagSet.Visit, so a flag is never considered set by its
+// default.
+func Validate(fs FlagSet, v interface{}, opts ...Option) error {
+	infos, err := Inspect(v, opts...)
+	if err != nil {
+		return err
+	}
+	set := setFlags(fs)
+	var groups flagGroups
+	for _, info := range infos {
+		groups.add(info, set[info.Name])
+		if !set[info.Name] {
+			continue
+		}
+		requires, _ := info.Option("requires")
+		for _, name := range splitList(requires) {
+			if !set[name] {
+				return ErrorFlagRequires{info.Name, name}
+			}
+		}
+		conflicts, _ := info.Option("conflicts")
+		for _, name := range splitList(conflicts) {
+			if set[name] {
+				return ErrorFlagConflicts{info.Name, name}
+			}
+		}
+	}
+	for _, group := range groups {
+		if err := group.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagGroups tracks the `oneof` and `at-most-one` groups in the order they
+// were first declared.
+type flagGroups []*flagGroup
+
+// add adds the flag described by info to its group, if any.
+func (groups *flagGroups) add(info FlagInfo, set bool) {
+	name, required := info.Option("oneof")
+	if !required {
+		name, _ = info.Option("at-most-one")
+	}
+	if name == "" {
+		return
+	}
+	for _, g := range *groups {
+		if g.Name == name {
+			g.add(info.Name, required, set)
+			return
+		}
+	}
+	g := &flagGroup{Name: name}
+	g.add(info.Name, required, set)
+	*groups = append(*groups, g)
+}
+
+// flagGroup tracks the flags in a `oneof` or `at-most-one` group.
+type flagGroup struct {
+	Name     string
+	Flags    []string
+	Set      []string
+	Required bool
+}
+
+func (g *flagGroup) add(name string, required, set bool) {
+	g.Flags = append(g.Flags, name)
+	g.Required = g.Required || required
+	if set {
+		g.Set = append(g.Set, name)
+	}
+}
+
+func (g *flagGroup) validate() error {
+	switch {
+	case len(g.Set) > 1:
+		return ErrorFlagConflicts{g.Set[0], g.Set[1]}
+	case len(g.Set) == 0 && g.Required:
+		return ErrorFlagOneOf{g.Name, g.Flags}
+	}
+	return nil
+}
+
+// setFlags returns the names of the flags that have been set in fs. A flag set
+// through one of its aliases is also reported as set.
+func setFlags(fs FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	add := func(name string, v flag.Value) {
+		set[name] = true
+		if name, ok := aliasOf(v); ok {
+			set[name] = true
+		}
+	}
+	switch fs := fs.(type) {
+	case STDFlagSet:
+		fs.Visit(func(f *flag.Flag) { add(f.Name, f.Value) })
+	case PFlagSet:
+		fs.Visit(func(f *pflag.Flag) { add(f.Name, f.Value) })
+	}
+	return set
+}
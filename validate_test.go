@@ -0,0 +1,129 @@
+package flagbind
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validateFlags struct {
+	TLS     bool `flag:";;;requires=tls-cert|tls-key"`
+	TLSCert string
+	TLSKey  string
+}
+
+func TestValidateRequires(t *testing.T) {
+	tests := []struct {
+		Name string
+		Args []string
+		Err  error
+	}{{
+		Name: "unset",
+	}, {
+		Name: "satisfied",
+		Args: []string{"--tls", "--tls-cert=c", "--tls-key=k"},
+	}, {
+		Name: "missing",
+		Args: []string{"--tls", "--tls-cert=c"},
+		Err:  ErrorFlagRequires{"tls", "tls-key"},
+	}, {
+		Name: "default",
+		Args: []string{"--tls"},
+		Err:  ErrorFlagRequires{"tls", "tls-cert"},
+	}}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			var f validateFlags
+			f.TLSCert = "default.pem"
+
+			pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+			require.NoError(t, Bind(pfs, &f))
+			require.NoError(t, pfs.Parse(test.Args))
+			assert.Equal(t, test.Err, Validate(pfs, &f))
+
+			var g validateFlags
+			g.TLSCert = "default.pem"
+
+			fs := flag.NewFlagSet("", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			require.NoError(t, Bind(fs, &g))
+			require.NoError(t, fs.Parse(test.Args))
+			assert.Equal(t, test.Err, Validate(fs, &g))
+		})
+	}
+
+	assert.EqualError(t, ErrorFlagRequires{"tls", "tls-key"},
+		`flag "tls" requires flag "tls-key" to be set`)
+}
+
+func TestValidateConflicts(t *testing.T) {
+	var f struct {
+		Verbose bool `flag:";;;conflicts=quiet"`
+		Quiet   bool
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--quiet"}))
+	assert.NoError(t, Validate(fs, &f))
+
+	require.NoError(t, fs.Parse([]string{"--verbose"}))
+	err := Validate(fs, &f)
+	assert.Equal(t, ErrorFlagConflicts{"verbose", "quiet"}, err)
+	assert.EqualError(t, err,
+		`flags "verbose" and "quiet" cannot be used together`)
+}
+
+func TestValidateOneOf(t *testing.T) {
+	type oneOfFlags struct {
+		File  string `flag:";;;oneof=input,aliases=in"`
+		URL   string `flag:";;;oneof=input,renamed-from=uri"`
+		Stdin bool   `flag:";;;oneof=input"`
+		JSON  bool   `flag:";;;at-most-one=format"`
+		YAML  bool   `flag:";;;at-most-one=format"`
+	}
+	tests := []struct {
+		Name string
+		Args []string
+		Err  error
+	}{{
+		Name: "one",
+		Args: []string{"-url", "x"},
+	}, {
+		Name: "alias",
+		Args: []string{"-in", "x"},
+	}, {
+		Name: "renamed-from",
+		Args: []string{"-uri", "x"},
+	}, {
+		Name: "none",
+		Args: []string{"-json"},
+		Err:  ErrorFlagOneOf{"input", []string{"file", "url", "stdin"}},
+	}, {
+		Name: "two",
+		Args: []string{"-stdin", "-file", "x"},
+		Err:  ErrorFlagConflicts{"file", "stdin"},
+	}, {
+		Name: "at-most-one",
+		Args: []string{"-stdin", "-json", "-yaml"},
+		Err:  ErrorFlagConflicts{"json", "yaml"},
+	}}
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			var f oneOfFlags
+			fs := flag.NewFlagSet("", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			require.NoError(t, Bind(fs, &f))
+			require.NoError(t, fs.Parse(test.Args))
+			assert.Equal(t, test.Err, Validate(fs, &f))
+		})
+	}
+
+	assert.EqualError(t, ErrorFlagOneOf{"input", []string{"file", "url"}},
+		`one of flags "file", "url" must be set`)
+}
@@ -3,6 +3,8 @@ package flagbind
 import (
 	"encoding"
 	"encoding/json"
+	"reflect"
+	"strconv"
 )
 
 type JSONRawMessage json.RawMessage
@@ -17,6 +19,109 @@ func (data JSONRawMessage) String() string {
 
 func (data JSONRawMessage) Type() string { return "JSON" }
 
+// mergeJSONRawMessage is a JSONRawMessage that deep merges JSON objects into
+// its existing value, instead of replacing it.
+type mergeJSONRawMessage json.RawMessage
+
+func (data *mergeJSONRawMessage) Set(text string) error {
+	var src interface{}
+	if err := json.Unmarshal([]byte(text), &src); err != nil {
+		return err
+	}
+	if len(*data) > 0 {
+		var dst interface{}
+		if err := json.Unmarshal(*data, &dst); err != nil {
+			return err
+		}
+		src = mergeJSON(dst, src)
+	}
+	merged, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	*data = merged
+	return nil
+}
+
+func (data mergeJSONRawMessage) String() string {
+	return string(data)
+}
+
+func (data mergeJSONRawMessage) Type() string { return "JSON" }
+
+// mergeJSON recursively merges the src object into the dst object. If either
+// is not an object, src replaces dst.
+func mergeJSON(dst, src interface{}) interface{} {
+	dstObj, ok := dst.(map[string]interface{})
+	if !ok {
+		return src
+	}
+	srcObj, ok := src.(map[string]interface{})
+	if !ok {
+		return src
+	}
+	for k, v := range srcObj {
+		dstObj[k] = mergeJSON(dstObj[k], v)
+	}
+	return dstObj
+}
+
+// countValue is an int flag.Value for the standard flag package that mirrors
+// pflag's Count. Each occurrence without a value increments the count.
+type countValue int
+
+func (c *countValue) Set(text string) error {
+	if text == "true" || text == "+1" {
+		*c++
+		return nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return err
+	}
+	*c = countValue(n)
+	return nil
+}
+
+func (c countValue) String() string { return strconv.Itoa(int(c)) }
+
+func (c countValue) IsBoolFlag() bool { return true }
+
+func (c countValue) Type() string { return "count" }
+
+// jsonValue is a flag.Value that unmarshals JSON into the value pointed to by
+// ptr, replacing its previous contents. If merge is set, the JSON is instead
+// unmarshaled on top of the previous contents.
+type jsonValue struct {
+	ptr   reflect.Value
+	merge bool
+}
+
+func (val *jsonValue) Set(text string) error {
+	if val.merge {
+		return json.Unmarshal([]byte(text), val.ptr.Interface())
+	}
+	v := reflect.New(val.ptr.Type().Elem())
+	if err := json.Unmarshal([]byte(text), v.Interface()); err != nil {
+		return err
+	}
+	val.ptr.Elem().Set(v.Elem())
+	return nil
+}
+
+func (val *jsonValue) String() string {
+	if !val.ptr.IsValid() {
+		return ""
+	}
+	data, err := json.Marshal(val.ptr.Interface())
+	if err != nil {
+		return "<invalid>"
+	}
+	return string(data)
+}
+
+func (val *jsonValue) Type() string { return "JSON" }
+
 type pflagMarshalerValue struct {
 	marshaler textBidiMarshaler
 	typeStr   string
@@ -0,0 +1,113 @@
+package flagbind
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// WatchSource is a source of Layers that may change, such as a config file.
+// See Watch.
+type WatchSource interface {
+	// Next blocks until the source has changed, or ctx is done, and
+	// returns its current Layers. The first call returns immediately.
+	Next(ctx context.Context) ([]Layer, error)
+}
+
+// Watch calls Resolve with the Layers from src each time it changes, until ctx
+// is done, so that the bound struct fields are updated at runtime. Flags set on
+// the command line are never changed. After each change, onChange, if not
+// nil, is called with the names of the flags whose values changed, or with
+// any error from src or Resolve, which does not stop Watch. Watch returns
+// ctx.Err().
+//
+// Watch sets the flags from its own goroutine, if it is called with go, so
+// the application must synchronize any access to the bound fields, such as
+// with the Synchronized Option. A flag that is removed from the source is
+// reset by Resolve to its struct field value or Flag Tag <default>.
+func Watch(ctx context.Context, src WatchSource, fs FlagSet,
+	onChange func(changed []string, err error)) error {
+	if onChange == nil {
+		onChange = func([]string, error) {}
+	}
+	for {
+		layers, err := src.Next(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			onChange(nil, err)
+			continue
+		}
+
+		before := flagValues(fs)
+		err = ResolveContext(ctx, fs, layers...)
+		var changed []string
+		for name, value := range flagValues(fs) {
+			if before[name] != value {
+				changed = append(changed, name)
+			}
+		}
+		if err != nil || len(changed) > 0 {
+			sort.Strings(changed)
+			onChange(changed, err)
+		}
+	}
+}
+
+// flagValues returns the String of the Value of each flag in fs.
+func flagValues(fs FlagSet) map[string]string {
+	values := make(map[string]string)
+	for _, setting := range Effective(fs) {
+		values[setting.Name] = setting.Value
+	}
+	return values
+}
+
+// JSONFileSource returns a WatchSource that polls the JSON config file at path
+// every interval, and returns a ConfigLayer from ReadJSONConfig whenever its
+// contents change. A missing file has no Layers.
+func JSONFileSource(path string, interval time.Duration) WatchSource {
+	return &jsonFileSource{path: path, interval: interval}
+}
+
+type jsonFileSource struct {
+	path     string
+	interval time.Duration
+	data     []byte
+	read     bool
+}
+
+func (src *jsonFileSource) Next(ctx context.Context) ([]Layer, error) {
+	ticker := time.NewTicker(src.interval)
+	defer ticker.Stop()
+	for {
+		if src.read {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-ticker.C:
+			}
+		}
+		data, err := ioutil.ReadFile(src.path)
+		if err != nil && !os.IsNotExist(err) {
+			src.data, src.read = nil, true
+			return nil, err
+		}
+		if src.read && bytes.Equal(data, src.data) {
+			continue
+		}
+		src.data, src.read = data, true
+		if len(data) == 0 {
+			return nil, nil
+		}
+		config, err := ReadJSONConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return []Layer{ConfigLayer(config)}, nil
+	}
+}
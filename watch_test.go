@@ -0,0 +1,118 @@
+package flagbind
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chanSource chan []Layer
+
+func (src chanSource) Next(ctx context.Context) ([]Layer, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case layers := <-src:
+		if layers == nil {
+			return nil, errors.New("unavailable")
+		}
+		return layers, nil
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var f struct {
+		Level string `flag:";info"`
+		Port  int
+		Tags  []string
+	}
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	require.NoError(t, Bind(fs, &f))
+	require.NoError(t, fs.Parse([]string{"--port", "80"}))
+
+	type change struct {
+		Changed []string
+		Err     error
+	}
+	changes := make(chan change)
+	src := make(chanSource)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, src, fs, func(changed []string, err error) {
+			changes <- change{changed, err}
+		})
+	}()
+
+	src <- []Layer{ConfigLayer(map[string]string{
+		"level": "debug", "port": "1"})}
+	assert.Equal(t, change{Changed: []string{"level"}}, <-changes)
+	assert.Equal(t, "debug", f.Level)
+	assert.Equal(t, 80, f.Port, "command line flags are not changed")
+
+	src <- nil
+	assert.EqualError(t, (<-changes).Err, "unavailable")
+
+	src <- []Layer{ConfigLayer(map[string]string{"level": "warn"})}
+	assert.Equal(t, change{Changed: []string{"level"}}, <-changes)
+	assert.Equal(t, "warn", f.Level)
+
+	// Reloading the same values reports no change, so the next change is
+	// only to level.
+	layers := []Layer{ConfigLayer(map[string]string{
+		"level": "warn", "tags": "a,b"})}
+	src <- layers
+	assert.Equal(t, change{Changed: []string{"tags"}}, <-changes)
+	src <- layers
+	src <- []Layer{ConfigLayer(map[string]string{
+		"level": "error", "tags": "a,b"})}
+	assert.Equal(t, change{Changed: []string{"level"}}, <-changes)
+	assert.Equal(t, []string{"a", "b"}, f.Tags)
+
+	src <- []Layer{ConfigLayer(map[string]string{"level": "error"})}
+	assert.Equal(t, change{Changed: []string{"tags"}}, <-changes)
+	assert.Empty(t, f.Tags)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
+
+func TestJSONFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flagbind")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+
+	src := JSONFileSource(path, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	layers, err := src.Next(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, layers, "missing file")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"level": "debug"}`),
+		0600))
+	layers, err = src.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	value, ok, err := layers[0].Lookup("level")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "debug", value)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{`), 0600))
+	_, err = src.Next(ctx)
+	assert.Error(t, err)
+
+	_, err = src.Next(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err, "unchanged file")
+}